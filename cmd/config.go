@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+)
+
+func runConfig(configFile string, args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		i18n.Fprintln(os.Stderr, "Usage: paypayo [-config config.yaml] config validate [fichier]")
+		os.Exit(2)
+	}
+	if len(args) > 1 {
+		configFile = args[1]
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s: %v\n", configFile, err)
+		os.Exit(1)
+	}
+	i18n.SetLanguage(cfg.Logging.Language)
+
+	i18n.Printf("✅ %s: configuration valide\n", configFile)
+}
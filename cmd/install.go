@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"app-db-listener/internal/database"
+	"app-db-listener/internal/i18n"
+)
+
+func runInstall(configFile string, args []string) {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	adopt := fs.Bool("adopt", false, i18n.T("Remplacer les objets de même nom créés avant les marqueurs paypayo"))
+	fs.Parse(args)
+
+	cfg, log := load(configFile)
+	defer log.Close()
+
+	installer, err := database.NewInstaller(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer installer.Close()
+
+	if err := installer.Install(context.Background(), *adopt); err != nil {
+		log.Error("Erreur installation: %v", err)
+		i18n.Fprintf(os.Stderr, "❌ Erreur installation: %v\n", err)
+		os.Exit(1)
+	}
+
+	i18n.Printf("✅ Objets paypayo installés pour la table '%s'\n", cfg.Database.Table)
+}
+
+func runUninstall(configFile string, args []string) {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	fs.Parse(args)
+
+	cfg, log := load(configFile)
+	defer log.Close()
+
+	installer, err := database.NewInstaller(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer installer.Close()
+
+	if err := installer.Uninstall(context.Background()); err != nil {
+		log.Error("Erreur désinstallation: %v", err)
+		i18n.Fprintf(os.Stderr, "❌ Erreur désinstallation: %v\n", err)
+		os.Exit(1)
+	}
+
+	i18n.Printf("✅ Objets paypayo supprimés pour la table '%s'\n", cfg.Database.Table)
+}
+
+func runGenerateSQL(configFile string, args []string) {
+	fs := flag.NewFlagSet("generate-sql", flag.ExitOnError)
+	output := fs.String("o", "", i18n.T("Fichier de sortie (sortie standard par défaut)"))
+	fs.Parse(args)
+
+	cfg := loadConfig(configFile)
+	// Le script occupe la sortie standard: les logs passent sur stderr.
+	if *output == "" {
+		for i, o := range cfg.Logging.Outputs {
+			if o == "stdout" {
+				cfg.Logging.Outputs[i] = "stderr"
+			}
+		}
+	}
+	log := openLogger(cfg)
+	defer log.Close()
+
+	installer, err := database.NewInstaller(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer installer.Close()
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := installer.GenerateSQL(context.Background(), w); err != nil {
+		i18n.Fprintf(os.Stderr, "❌ Erreur génération SQL: %v\n", err)
+		os.Exit(1)
+	}
+}
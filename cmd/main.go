@@ -6,85 +6,197 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"app-db-listener/internal/config"
 	"app-db-listener/internal/database"
+	"app-db-listener/internal/i18n"
 	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
 	"app-db-listener/internal/notifier"
 )
 
 func main() {
-	configFile := flag.String("config", "config.yaml", "Chemin du fichier de configuration")
+	// Langue de l'environnement pour les messages affichés avant la lecture
+	// de la configuration.
+	i18n.SetLanguage(os.Getenv("PAYPAYO_LOGGING_LANGUAGE"))
+
+	configFile := flag.String("config", "config.yaml", i18n.T("Chemin du fichier de configuration"))
+	flag.Usage = usage
 	flag.Parse()
 
-	cfg, err := config.Load(*configFile)
+	switch flag.Arg(0) {
+	case "", "run":
+		run(*configFile)
+	case "snapshot":
+		runSnapshot(*configFile, flag.Args()[1:])
+	case "config":
+		runConfig(*configFile, flag.Args()[1:])
+	case "install":
+		runInstall(*configFile, flag.Args()[1:])
+	case "uninstall":
+		runUninstall(*configFile, flag.Args()[1:])
+	case "generate-sql":
+		runGenerateSQL(*configFile, flag.Args()[1:])
+	default:
+		i18n.Fprintf(os.Stderr, "Commande inconnue: %s\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	i18n.Fprintf(os.Stderr, `Usage: paypayo [-config config.yaml] [commande]
+
+Commandes:
+  run        écoute la table et envoie les notifications (par défaut)
+  snapshot   envoie le contenu actuel de la table (événements SNAPSHOT)
+  install [-adopt]
+             crée ou met à jour les objets paypayo (triggers, fonctions, tables d'audit)
+  uninstall  supprime les objets créés par paypayo, et seulement ceux-là
+  generate-sql [-o fichier]
+             affiche le SQL d'installation à faire appliquer par un DBA
+  config validate [fichier]
+             vérifie la configuration sans se connecter à la base
+
+Options:
+`)
+	flag.PrintDefaults()
+}
+
+func load(configFile string) (*config.Config, *logger.Logger) {
+	cfg := loadConfig(configFile)
+	return cfg, openLogger(cfg)
+}
+
+func loadConfig(configFile string) *config.Config {
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erreur chargement configuration: %v\n", err)
+		i18n.Fprintf(os.Stderr, "Erreur chargement configuration: %v\n", err)
 		os.Exit(1)
 	}
+	i18n.SetLanguage(cfg.Logging.Language)
+	return cfg
+}
 
-	log, err := logger.New(cfg.Logging.File, cfg.Logging.Level)
+func openLogger(cfg *config.Config) *logger.Logger {
+	log, err := logger.New(&cfg.Logging)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erreur initialisation logger: %v\n", err)
+		i18n.Fprintf(os.Stderr, "Erreur initialisation logger: %v\n", err)
 		os.Exit(1)
 	}
+	return log
+}
+
+func run(configFile string) {
+	cfg, log := load(configFile)
 	defer log.Close()
 
-	banner := `
+	i18n.Println(`
 ╔════════════════════════════════════════════════════════════════╗
 ║ 🚀 Paypayo: DB Listerner - application de surveillance de table ║
 ╚════════════════════════════════════════════════════════════════╝
-`
-	fmt.Println(banner)
+`)
 
 	log.Info("=== Démarrage de l'application DB Listener ===")
 
-	fmt.Printf("📊 Configuration:\n")
-	fmt.Printf("   └─ Base de données : %s\n", cfg.Database.Type)
-	fmt.Printf("   └─ Hôte           : %s:%d\n", cfg.Database.Host, cfg.Database.Port)
-	fmt.Printf("   └─ Database       : %s\n", cfg.Database.Database)
-	fmt.Printf("   └─ Table          : %s\n", cfg.Database.Table)
-	fmt.Printf("   └─ SSL Mode       : %s\n", cfg.Database.SSLMode)
+	i18n.Printf("📊 Configuration:\n")
+	i18n.Printf("   └─ Base de données : %s\n", cfg.Database.Type)
+	if cfg.Database.Type == "sqlite" {
+		i18n.Printf("   └─ Fichier        : %s\n", cfg.Database.Database)
+	} else {
+		i18n.Printf("   └─ Hôte           : %s:%d\n", cfg.Database.Host, cfg.Database.Port)
+		i18n.Printf("   └─ Database       : %s\n", cfg.Database.Database)
+	}
+	i18n.Printf("   └─ Table          : %s\n", cfg.Database.Table)
+	i18n.Printf("   └─ SSL Mode       : %s\n", cfg.Database.SSLMode)
+	if cfg.HA.Mode != "none" {
+		i18n.Printf("   └─ HA             : %s\n", cfg.HA.Mode)
+	}
 	fmt.Println()
 
-	fmt.Printf("🎯 Modes d'écoute:\n")
+	i18n.Printf("🎯 Modes d'écoute:\n")
 	if cfg.Listener.IsInsertEnabled() {
-		fmt.Printf("   ✅ INSERT activé\n")
+		i18n.Printf("   ✅ INSERT activé\n")
 	}
 	if cfg.Listener.IsUpdateEnabled() {
-		fmt.Printf("   ✅ UPDATE activé\n")
+		i18n.Printf("   ✅ UPDATE activé\n")
 	}
 	if cfg.Listener.IsDeleteEnabled() {
-		fmt.Printf("   ✅ DELETE activé\n")
+		i18n.Printf("   ✅ DELETE activé\n")
 	}
-	if cfg.Database.Type == "mysql" {
-		fmt.Printf("   └─ Polling : toutes les %d secondes\n", cfg.Listener.PollInterval)
+	if cfg.Database.Type != "postgres" {
+		i18n.Printf("   └─ Polling : toutes les %d secondes\n", cfg.Listener.PollInterval)
+	}
+	if cfg.Listener.SnapshotOnStart {
+		i18n.Printf("   └─ Snapshot initial activé\n")
 	}
 	fmt.Println()
 
-	fmt.Printf("🌐 Webhook:\n")
-	fmt.Printf("   └─ URL     : %s\n", cfg.Webhook.URL)
-	fmt.Printf("   └─ Timeout : %ds\n", cfg.Webhook.Timeout)
-	fmt.Printf("   └─ Retries : %d tentatives\n", cfg.Webhook.RetryCount)
+	i18n.Printf("🌐 Webhook:\n")
+	i18n.Printf("   └─ URL     : %s\n", config.RedactURL(cfg.Webhook.URL))
+	i18n.Printf("   └─ Timeout : %ds\n", cfg.Webhook.Timeout)
+	i18n.Printf("   └─ Retries : %d tentatives\n", cfg.Webhook.RetryCount)
+	i18n.Printf("   └─ Format  : %s\n", cfg.Webhook.Format)
+	for i, d := range cfg.Webhook.Destinations {
+		name, kind := d.Name, d.Type
+		if name == "" {
+			name = i18n.Sprintf("destination %d", i+1)
+		}
+		if kind == "" {
+			kind = "webhook"
+		}
+		i18n.Printf("   └─ Destination : %s (%s) %s\n", name, kind, config.RedactURL(d.URL))
+	}
+	if cfg.GRPC.Addr != "" {
+		i18n.Printf("   └─ gRPC    : %s\n", cfg.GRPC.Addr)
+	}
+	if cfg.GRPC.PushTarget != "" {
+		i18n.Printf("   └─ Push    : %s\n", cfg.GRPC.PushTarget)
+	}
+	if cfg.HTTP.Addr != "" {
+		i18n.Printf("   └─ Flux    : %s/events (SSE, WebSocket)\n", cfg.HTTP.Addr)
+	}
 	fmt.Println()
 
-	fmt.Printf("⚙️  Workers:\n")
-	fmt.Printf("   └─ Pool size : %d workers\n", cfg.Worker.PoolSize)
+	i18n.Printf("⚙️  Workers:\n")
+	i18n.Printf("   └─ Pool size : %d workers\n", cfg.Worker.PoolSize)
+	i18n.Printf("   └─ File      : %d événements (politique: %s)\n", cfg.Worker.QueueSize, cfg.Worker.QueuePolicy)
 	fmt.Println()
 
-	fmt.Printf("📝 Logs:\n")
-	fmt.Printf("   └─ Fichier : %s\n", cfg.Logging.File)
-	fmt.Printf("   └─ Niveau  : %s\n", cfg.Logging.Level)
+	i18n.Printf("📝 Logs:\n")
+	i18n.Printf("   └─ Sorties : %s\n", strings.Join(cfg.Logging.Outputs, ", "))
+	if cfg.Logging.HasOutput("file") {
+		i18n.Printf("   └─ Fichier : %s\n", cfg.Logging.File)
+	}
+	i18n.Printf("   └─ Niveau  : %s\n", cfg.Logging.Level)
+	i18n.Printf("   └─ Langue  : %s\n", cfg.Logging.Language)
+	i18n.Printf("   └─ Format  : %s\n", cfg.Logging.Format)
 	fmt.Println()
 
 	log.Info("Type de base de données: %s", cfg.Database.Type)
 	log.Info("Table surveillée: %s", cfg.Database.Table)
 	log.Info("Modes activés: %s", cfg.Listener.Modes)
-	log.Info("URL webhook: %s", cfg.Webhook.URL)
+	log.Info("URL webhook: %s", config.RedactURL(cfg.Webhook.URL))
 	log.Info("Workers: %d", cfg.Worker.PoolSize)
 
-	ntf := notifier.New(&cfg.Webhook, log)
+	if cfg.Metrics.Addr != "" {
+		metrics.Serve(cfg.Metrics.Addr, log)
+	}
+
+	ntf, err := notifier.New(&cfg.Webhook, log)
+	if err != nil {
+		log.Error("Erreur initialisation notifier: %v", err)
+		os.Exit(1)
+	}
+
+	stopSinks, err := startSinks(cfg, log, ntf)
+	if err != nil {
+		log.Error("Erreur initialisation des flux: %v", err)
+		os.Exit(1)
+	}
+	defer stopSinks()
 
 	listener, err := database.NewListener(cfg, log, ntf)
 	if err != nil {
@@ -104,31 +216,62 @@ func main() {
 		errCh <- listener.Listen(ctx)
 	}()
 
+	if cfg.Listener.SnapshotOnStart {
+		go func() {
+			if err := listener.Snapshot(ctx); err != nil && ctx.Err() == nil {
+				log.Error("Erreur snapshot: %v", err)
+			}
+		}()
+	}
+
 	log.Info("Application démarrée et en écoute...")
-	fmt.Println("✨ Application démarrée avec succès!")
-	fmt.Printf("👀 Surveillance active sur la table '%s'\n", cfg.Database.Table)
-	fmt.Println("📡 En attente d'événements...")
+	i18n.Println("✨ Application démarrée avec succès!")
+	i18n.Printf("👀 Surveillance active sur la table '%s'\n", cfg.Database.Table)
+	i18n.Println("📡 En attente d'événements...")
 	fmt.Println()
-	fmt.Println("💡 Conseil: Pour exécuter en arrière-plan, utilisez 'nohup' ou 'systemd'")
-	fmt.Println("   Exemple: nohup ./paypayo-1.0.0 &")
+	i18n.Println("💡 Conseil: Pour exécuter en arrière-plan, utilisez 'nohup' ou 'systemd'")
+	i18n.Println("   Exemple: nohup ./paypayo-1.0.0 &")
 	fmt.Println()
-	fmt.Println("⏹️  Pour arrêter: Ctrl+C ou kill -SIGTERM <PID>")
+	i18n.Println("🔁 Pour recharger la configuration: kill -SIGHUP <PID>")
+	i18n.Println("📝 Pour rouvrir le fichier de log (logrotate): kill -SIGUSR1 <PID>")
+	i18n.Println("⏹️  Pour arrêter: Ctrl+C ou kill -SIGTERM <PID>")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-	select {
-	case <-sigCh:
-		fmt.Println("\n🛑 Signal d'arrêt reçu...")
-		log.Info("Signal d'arrêt reçu, fermeture de l'application...")
-		cancel()
-	case err := <-errCh:
-		if err != nil && err != context.Canceled {
-			fmt.Printf("\n❌ Erreur: %v\n", err)
-			log.Error("Erreur du listener: %v", err)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
+	usr1Ch := make(chan os.Signal, 1)
+	signal.Notify(usr1Ch, syscall.SIGUSR1)
+
+	current := cfg
+
+loop:
+	for {
+		select {
+		case <-hupCh:
+			current = reload(configFile, current, log, ntf, listener)
+		case <-usr1Ch:
+			if err := log.Reopen(); err != nil {
+				i18n.Fprintf(os.Stderr, "Erreur réouverture du fichier de log: %v\n", err)
+			}
+			log.Info("SIGUSR1 reçu, fichier de log rouvert")
+		case <-sigCh:
+			i18n.Println("\n🛑 Signal d'arrêt reçu...")
+			log.Info("Signal d'arrêt reçu, fermeture de l'application...")
+			cancel()
+			break loop
+		case err := <-errCh:
+			if err != nil && err != context.Canceled {
+				i18n.Printf("\n❌ Erreur: %v\n", err)
+				log.Error("Erreur du listener: %v", err)
+			}
+			break loop
 		}
 	}
 
-	fmt.Println("🔄 Fermeture en cours...")
+	i18n.Println("🔄 Fermeture en cours...")
+	log.Info("Métriques: %s", metrics.String())
 	log.Info("=== Application arrêtée ===")
-	fmt.Println("✅ Application arrêtée proprement")
+	i18n.Println("✅ Application arrêtée proprement")
 	fmt.Println()
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/database"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+)
+
+// reload relit et valide le fichier de configuration, applique les
+// changements sans risque et signale ceux qui demandent un redémarrage.
+// La configuration effectivement appliquée est renvoyée.
+func reload(configFile string, current *config.Config, log *logger.Logger, ntf *notifier.Notifier, listener database.Listener) *config.Config {
+	log.Info("SIGHUP reçu, rechargement de %s", configFile)
+
+	next, err := config.Load(configFile)
+	if err != nil {
+		log.Error("Rechargement refusé, configuration actuelle conservée: %v", err)
+		return current
+	}
+
+	plan := config.PlanReload(current, next)
+	if plan.Empty() {
+		log.Info("Rechargement: aucun changement")
+		return current
+	}
+
+	if len(plan.Live) > 0 {
+		// La langue précède le notifier: le message par défaut des
+		// messageries est traduit à la compilation des destinations.
+		i18n.SetLanguage(next.Logging.Language)
+		if err := ntf.Reload(&next.Webhook); err != nil {
+			i18n.SetLanguage(current.Logging.Language)
+			log.Error("Rechargement refusé, configuration actuelle conservée: %v", err)
+			return current
+		}
+		log.SetLevel(next.Logging.Level)
+		if next.Worker.PoolSize != current.Worker.PoolSize {
+			listener.SetPoolSize(next.Worker.PoolSize)
+		}
+		log.Info("Rechargement: changements appliqués: %s", strings.Join(plan.Live, ", "))
+	}
+
+	if len(plan.Triggers) > 0 {
+		log.Warn("Rechargement: changements non appliqués, ils nécessitent de réinstaller les triggers (redémarrage): %s",
+			strings.Join(plan.Triggers, ", "))
+	}
+
+	if len(plan.Restart) > 0 {
+		log.Warn("Rechargement: changements pris en compte au prochain démarrage: %s", strings.Join(plan.Restart, ", "))
+	}
+
+	return current.WithLive(next)
+}
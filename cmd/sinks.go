@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/feed"
+	"app-db-listener/internal/grpcapi"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/stream"
+)
+
+// startSinks branche sur le notifier les flux gRPC et HTTP et le mode push, selon la
+// configuration. La fonction renvoyée les arrête.
+func startSinks(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	// Le hub est partagé par le flux gRPC et le flux HTTP
+	if cfg.GRPC.Addr != "" || cfg.HTTP.Addr != "" {
+		hub, err := stream.New(&cfg.Stream, log)
+		if err != nil {
+			return nil, err
+		}
+		ntf.AddSink(hub)
+		// La fermeture du hub termine les flux en cours, qu'attendent
+		// GracefulStop et Shutdown
+		var servers []func()
+		stops = append(stops, func() {
+			hub.Close()
+			for _, s := range servers {
+				s()
+			}
+		})
+
+		if cfg.GRPC.Addr != "" {
+			srv, err := grpcapi.Serve(&cfg.GRPC, hub, log)
+			if err != nil {
+				stop()
+				return nil, err
+			}
+			servers = append(servers, srv.GracefulStop)
+		}
+
+		if cfg.HTTP.Addr != "" {
+			srv, err := feed.Serve(&cfg.HTTP, hub, log)
+			if err != nil {
+				stop()
+				return nil, err
+			}
+			servers = append(servers, func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			})
+		}
+	}
+
+	if cfg.GRPC.PushTarget != "" {
+		pusher, err := grpcapi.NewPusher(&cfg.GRPC, log)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		ntf.AddSink(pusher)
+		stops = append(stops, func() { pusher.Close() })
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"app-db-listener/internal/database"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/notifier"
+)
+
+func runSnapshot(configFile string, args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	reset := fs.Bool("reset", false, i18n.T("Ignorer le checkpoint et repartir du début de la table"))
+	fs.Parse(args)
+
+	cfg, log := load(configFile)
+	defer log.Close()
+
+	if *reset {
+		if err := database.ResetSnapshot(cfg); err != nil {
+			i18n.Fprintf(os.Stderr, "Erreur suppression checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Le snapshot s'exécute à côté de l'instance active, sans attendre son
+	// verrou.
+	if cfg.HA.Mode == "leader" {
+		cfg.HA.Mode = "none"
+	}
+
+	ntf, err := notifier.New(&cfg.Webhook, log)
+	if err != nil {
+		log.Error("Erreur initialisation notifier: %v", err)
+		i18n.Fprintf(os.Stderr, "Erreur initialisation notifier: %v\n", err)
+		os.Exit(1)
+	}
+
+	listener, err := database.NewListener(cfg, log, ntf)
+	if err != nil {
+		log.Error("Erreur initialisation listener: %v", err)
+		i18n.Fprintf(os.Stderr, "Erreur initialisation listener: %v\n", err)
+		os.Exit(1)
+	}
+	defer listener.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	i18n.Printf("📸 Snapshot de la table '%s' en cours...\n", cfg.Database.Table)
+
+	if err := listener.Snapshot(ctx); err != nil {
+		log.Error("Erreur snapshot: %v", err)
+		i18n.Fprintf(os.Stderr, "❌ Erreur snapshot: %v\n", err)
+		i18n.Fprintln(os.Stderr, "   Relancez la commande pour reprendre au dernier checkpoint.")
+		os.Exit(1)
+	}
+
+	i18n.Println("✅ Snapshot terminé")
+}
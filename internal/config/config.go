@@ -1,11 +1,15 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"net/url"
 	"os"
+	"reflect"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"app-db-listener/internal/i18n"
 )
 
 type Config struct {
@@ -14,6 +18,12 @@ type Config struct {
 	Webhook  WebhookConfig  `yaml:"webhook"`
 	Logging  LoggingConfig  `yaml:"logging"`
 	Worker   WorkerConfig   `yaml:"worker"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Audit    AuditConfig    `yaml:"audit"`
+	HA       HAConfig       `yaml:"ha"`
+	Stream   StreamConfig   `yaml:"stream"`
+	GRPC     GRPCConfig     `yaml:"grpc"`
+	HTTP     HTTPConfig     `yaml:"http"`
 }
 
 type DatabaseConfig struct {
@@ -24,12 +34,28 @@ type DatabaseConfig struct {
 	Password string `yaml:"password"`
 	Database string `yaml:"database"`
 	Table    string `yaml:"table"`
+	Schema   string `yaml:"schema"`
 	SSLMode  string `yaml:"sslmode"`
+	// Schéma PostgreSQL dans lequel paypayo crée ses fonctions.
+	ObjectsSchema string `yaml:"objects_schema"`
+	// false: les objets sont créés par un DBA (generate-sql), paypayo se
+	// contente de vérifier qu'ils existent et sont à jour.
+	ManageTriggers bool `yaml:"manage_triggers"`
 }
 
 type ListenerConfig struct {
 	Modes        string `yaml:"modes"`
 	PollInterval int    `yaml:"poll_interval"`
+	// Polling MySQL adaptatif: relance immédiate tant que les lots sont
+	// pleins, attente doublée jusqu'à max_poll_interval quand rien n'arrive.
+	MaxPollInterval    int    `yaml:"max_poll_interval"`
+	BatchSize          int    `yaml:"batch_size"`
+	SnapshotOnStart    bool   `yaml:"snapshot_on_start"`
+	SnapshotChunkSize  int    `yaml:"snapshot_chunk_size"`
+	SnapshotCheckpoint string `yaml:"snapshot_checkpoint"`
+	// Intervalle en secondes de détection des changements de colonnes (MySQL),
+	// 0 pour désactiver.
+	SchemaCheckInterval int `yaml:"schema_check_interval"`
 }
 
 type WebhookConfig struct {
@@ -37,39 +63,244 @@ type WebhookConfig struct {
 	Timeout    int    `yaml:"timeout"`
 	RetryCount int    `yaml:"retry_count"`
 	RetryDelay int    `yaml:"retry_delay"`
+	// Joindre la section schema (types des colonnes) à chaque événement.
+	IncludeSchema bool `yaml:"include_schema"`
+	// Format du corps envoyé: json (événement paypayo), cloudevents ou
+	// debezium.
+	Format string `yaml:"format"`
+	// CloudEvents: structured (application/cloudevents+json) ou binary
+	// (attributs dans les en-têtes ce-*).
+	CloudEventsMode string `yaml:"cloudevents_mode"`
+	// Attribut source des CloudEvents, /paypayo/<type>/<database> si vide.
+	CloudEventsSource string `yaml:"cloudevents_source"`
+	// Destinations recevant chacune les événements; vide: webhook.url seul.
+	Destinations []DestinationConfig `yaml:"destinations"`
+}
+
+// DestinationConfig décrit un récepteur et, en templates text/template,
+// la requête qui lui est envoyée.
+type DestinationConfig struct {
+	Name string `yaml:"name"`
+	// webhook (défaut), slack, teams, discord ou telegram.
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"` // webhook.url si vide
+	// Templates ajoutés à l'URL: chemin et query string (a=1&b=2).
+	Path    string            `yaml:"path"`
+	Query   string            `yaml:"query"`
+	Headers map[string]string `yaml:"headers"`
+	// Corps de la requête; vide: événement au format webhook.format.
+	Body        string `yaml:"body"`
+	ContentType string `yaml:"content_type"`
+	// Messageries: template du texte envoyé, message par défaut du type si
+	// vide. Telegram demande aussi le jeton du bot et le chat.
+	Message string `yaml:"message"`
+	Token   string `yaml:"token"`
+	ChatID  string `yaml:"chat_id"`
+	// Filtres: opérations envoyées (toutes si vide) et condition en template,
+	// l'événement est envoyé si elle produit "true".
+	Operations []string `yaml:"operations"`
+	When       string   `yaml:"when"`
+}
+
+// IsChat indique une destination de messagerie (texte mis en forme).
+func (d *DestinationConfig) IsChat() bool {
+	return d.Type != "" && d.Type != "webhook"
+}
+
+// RedactURL réduit une url au schéma et à l'hôte pour l'affichage: le chemin
+// et la query string des webhooks entrants (Slack, Teams, Discord) sont le
+// secret qui autorise l'envoi.
+func RedactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "***"
+	}
+	if u.Path == "" && u.RawQuery == "" && u.User == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return u.Scheme + "://" + u.Host + "/***"
 }
 
 type LoggingConfig struct {
-	File  string `yaml:"file"`
-	Level string `yaml:"level"`
+	File   string `yaml:"file"`
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"` // text ou json
+	// Langue des messages (logs, erreurs, console): fr ou en.
+	Language string `yaml:"language"`
+	// Sorties: file, stdout, stderr, syslog; plusieurs possibles.
+	Outputs  []string          `yaml:"outputs"`
+	Rotation LogRotationConfig `yaml:"rotation"`
+	Syslog   SyslogConfig      `yaml:"syslog"`
+}
+
+// LogRotationConfig règle la rotation du fichier de log; les valeurs à 0
+// désactivent le critère correspondant.
+type LogRotationConfig struct {
+	MaxSizeMB     int  `yaml:"max_size_mb"`
+	IntervalHours int  `yaml:"interval_hours"`
+	MaxBackups    int  `yaml:"max_backups"`
+	MaxAgeDays    int  `yaml:"max_age_days"`
+	Compress      bool `yaml:"compress"` // gzip des fichiers tournés
+}
+
+type SyslogConfig struct {
+	Network string `yaml:"network"` // udp ou tcp; vide pour le syslog local
+	Address string `yaml:"address"`
+	Tag     string `yaml:"tag"`
+}
+
+// HasOutput indique si la sortie est activée.
+func (c *LoggingConfig) HasOutput(name string) bool {
+	return contains(c.Outputs, name)
 }
 
 type WorkerConfig struct {
-	PoolSize int `yaml:"pool_size"`
+	PoolSize    int    `yaml:"pool_size"`
+	QueueSize   int    `yaml:"queue_size"`
+	QueuePolicy string `yaml:"queue_policy"` // block, spill_to_disk, drop
+	SpillDir    string `yaml:"spill_dir"`
+}
+
+type MetricsConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// HAConfig coordonne plusieurs instances surveillant la même table.
+type HAConfig struct {
+	Mode          string `yaml:"mode"`           // none, leader, shared (MySQL)
+	LockName      string `yaml:"lock_name"`      // par défaut paypayo:<database>.<table>
+	RetryInterval int    `yaml:"retry_interval"` // secondes entre deux tentatives de prise du verrou
+}
+
+// AuditConfig règle le nettoyage de la table d'audit MySQL.
+type AuditConfig struct {
+	RetentionHours  int  `yaml:"retention_hours"`  // 0: lignes traitées conservées indéfiniment
+	CleanupInterval int  `yaml:"cleanup_interval"` // secondes
+	BatchSize       int  `yaml:"batch_size"`
+	Archive         bool `yaml:"archive"` // copier dans <table>_audit_archive avant suppression
+	PartitionByDay  bool `yaml:"partition_by_day"`
+}
+
+// StreamConfig règle le flux d'événements diffusé aux abonnés (gRPC): les
+// derniers événements sont gardés en mémoire, et dans un journal sur disque
+// si journal est défini, pour permettre la reprise.
+type StreamConfig struct {
+	BufferSize   int    `yaml:"buffer_size"`    // événements gardés en mémoire
+	ClientBuffer int    `yaml:"client_buffer"`  // événements en attente par abonné
+	Journal      string `yaml:"journal"`        // fichier journal, vide: mémoire seulement
+	JournalMaxMB int    `yaml:"journal_max_mb"` // taille avant rotation (un fichier .1 conservé)
 }
 
+// GRPCConfig expose le flux par gRPC (Subscribe) et/ou pousse chaque
+// événement vers un service ChangeSink.
+type GRPCConfig struct {
+	Addr    string `yaml:"addr"` // ex: ":50051", vide: serveur désactivé
+	TLSCert string `yaml:"tls_cert"`
+	TLSKey  string `yaml:"tls_key"`
+	// Mode push: adresse du service ChangeSink appelé pour chaque événement.
+	PushTarget     string `yaml:"push_target"`
+	PushTLS        bool   `yaml:"push_tls"`
+	PushTimeout    int    `yaml:"push_timeout"` // secondes
+	PushRetryCount int    `yaml:"push_retry_count"`
+	PushRetryDelay int    `yaml:"push_retry_delay"` // secondes
+}
+
+// HTTPConfig expose le flux en SSE et WebSocket sur /events.
+type HTTPConfig struct {
+	Addr    string `yaml:"addr"`  // ex: ":8080", vide: serveur désactivé
+	Token   string `yaml:"token"` // jeton exigé des clients, vide: accès libre
+	TLSCert string `yaml:"tls_cert"`
+	TLSKey  string `yaml:"tls_key"`
+	// Origines autorisées (CORS, WebSocket), toutes si "*".
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Enabled indique si le serveur ou le mode push gRPC est configuré.
+func (g *GRPCConfig) Enabled() bool {
+	return g.Addr != "" || g.PushTarget != ""
+}
+
+// Load lit le fichier YAML, applique les valeurs par défaut et les
+// surcharges d'environnement, puis valide le résultat. Les clés inconnues, les
+// erreurs de type et les valeurs invalides sont renvoyées ensemble dans une
+// ValidationError.
 func Load(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("erreur lecture fichier config: %w", err)
+		return nil, i18n.Errorf("erreur lecture fichier config: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, i18n.Errorf("erreur parsing config: %w", err)
+	}
+
+	if err := resolveNode(&root); err != nil {
+		return nil, i18n.Errorf("erreur résolution config: %w", err)
+	}
+
+	cfg := Default()
+	var problems []string
+
+	if root.Kind != 0 {
+		if err := root.Decode(cfg); err != nil {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) {
+				return nil, i18n.Errorf("erreur parsing config: %w", err)
+			}
+			problems = append(problems, typeErr.Errors...)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, i18n.Errorf("erreur variables d'environnement: %w", err)
+	}
+
+	cfg.applyTypeDefaults()
+
+	// Les clés inconnues sont signalées dans la langue choisie par le fichier.
+	problems = append(checkKnownFields(&root, reflect.TypeOf(*cfg), "", i18n.For(cfg.Logging.Language)), problems...)
+
+	if err := cfg.Validate(); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			problems = append(problems, verr.Problems...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems, lang: cfg.Logging.Language}
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("erreur parsing config: %w", err)
+	return cfg, nil
+}
+
+// ModeList renvoie les modes configurés, normalisés en minuscules.
+func (c *ListenerConfig) ModeList() []string {
+	var modes []string
+	for _, mode := range strings.Split(c.Modes, ",") {
+		if mode = strings.ToLower(strings.TrimSpace(mode)); mode != "" {
+			modes = append(modes, mode)
+		}
 	}
+	return modes
+}
 
-	return &cfg, nil
+func (c *ListenerConfig) hasMode(mode string) bool {
+	return contains(c.ModeList(), mode)
 }
 
 func (c *ListenerConfig) IsInsertEnabled() bool {
-	return strings.Contains(strings.ToLower(c.Modes), "insert")
+	return c.hasMode("insert")
 }
 
 func (c *ListenerConfig) IsUpdateEnabled() bool {
-	return strings.Contains(strings.ToLower(c.Modes), "update")
+	return c.hasMode("update")
 }
 
 func (c *ListenerConfig) IsDeleteEnabled() bool {
-	return strings.Contains(strings.ToLower(c.Modes), "delete")
+	return c.hasMode("delete")
 }
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"app-db-listener/internal/i18n"
+)
+
+// Les valeurs de configuration sont résolues dans cet ordre, de la plus
+// prioritaire à la moins prioritaire:
+//
+//  1. la variable d'environnement PAYPAYO_<SECTION>_<CHAMP>
+//  2. le fichier désigné par PAYPAYO_<SECTION>_<CHAMP>_FILE
+//  3. le fichier désigné par la clé YAML <champ>_file
+//  4. la clé YAML <champ>, après interpolation des ${VAR}
+const envPrefix = "PAYPAYO"
+
+// resolveNode interpole les ${VAR} dans les valeurs scalaires et remplace les
+// clés <champ>_file par le contenu du fichier correspondant.
+func resolveNode(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := resolveNode(child); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		return resolveMapping(node)
+	case yaml.ScalarNode:
+		value, err := interpolate(node.Value)
+		if err != nil {
+			return i18n.Errorf("ligne %d: %w", node.Line, err)
+		}
+		node.Value = value
+	}
+	return nil
+}
+
+func resolveMapping(node *yaml.Node) error {
+	fromFile := make(map[string]*yaml.Node)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if err := resolveNode(value); err != nil {
+			return err
+		}
+
+		name, ok := strings.CutSuffix(key.Value, "_file")
+		if !ok || value.Kind != yaml.ScalarNode || value.Value == "" {
+			continue
+		}
+
+		secret, err := readSecretFile(value.Value)
+		if err != nil {
+			return i18n.Errorf("ligne %d: %s: %w", key.Line, key.Value, err)
+		}
+		fromFile[name] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: secret, Line: value.Line}
+	}
+
+	if len(fromFile) == 0 {
+		return nil
+	}
+
+	// Le fichier l'emporte sur la valeur en clair; les clés *_file disparaissent.
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if name, ok := strings.CutSuffix(key.Value, "_file"); ok && fromFile[name] != nil {
+			continue
+		}
+		if _, ok := fromFile[key.Value]; ok {
+			continue
+		}
+		content = append(content, key, node.Content[i+1])
+	}
+	for name, value := range fromFile {
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name, Line: value.Line}, value)
+	}
+	node.Content = content
+
+	return nil
+}
+
+// interpolate remplace ${VAR} et ${VAR:-défaut}. "$${" produit un "${" littéral.
+func interpolate(value string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+
+		if start > 0 && value[start-1] == '$' {
+			b.WriteString(value[:start-1])
+			b.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", i18n.Errorf("accolade fermante manquante dans %q", value)
+		}
+		end += start
+
+		b.WriteString(value[:start])
+
+		name, def, hasDefault := strings.Cut(value[start+2:end], ":-")
+		if env, ok := os.LookupEnv(name); ok && (env != "" || !hasDefault) {
+			b.WriteString(env)
+		} else if hasDefault {
+			b.WriteString(def)
+		} else {
+			return "", i18n.Errorf("variable d'environnement %s non définie", name)
+		}
+
+		value = value[end+1:]
+	}
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", i18n.Errorf("erreur lecture fichier secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// applyEnv applique les variables PAYPAYO_* sur les champs de la configuration.
+func applyEnv(cfg *Config) error {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), envPrefix)
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		envName := prefix + "_" + strings.ToUpper(name)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnvStruct(fv, envName); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			path, ok := os.LookupEnv(envName + "_FILE")
+			if !ok {
+				continue
+			}
+			secret, err := readSecretFile(path)
+			if err != nil {
+				return i18n.Errorf("%s_FILE: %w", envName, err)
+			}
+			value = secret
+		}
+
+		if err := setField(fv, value); err != nil {
+			return i18n.Errorf("%s: %w", envName, err)
+		}
+	}
+
+	return nil
+}
+
+func setField(fv reflect.Value, value string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return i18n.Errorf("entier attendu: %q", value)
+		}
+		fv.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return i18n.Errorf("booléen attendu: %q", value)
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return i18n.Errorf("type non supporté par les variables d'environnement")
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return i18n.Errorf("type non supporté par les variables d'environnement")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Champs appliqués à chaud lors d'un rechargement. Les préfixes se terminant
+// par "." couvrent toute la section.
+var (
+	liveFields    = []string{"webhook.", "logging.level", "logging.language", "worker.pool_size"}
+	triggerFields = []string{"database.", "listener.modes"}
+)
+
+// ReloadPlan classe les champs modifiés entre deux configurations.
+type ReloadPlan struct {
+	Live     []string // appliqués immédiatement
+	Triggers []string // nécessitent de réinstaller les triggers, non appliqués
+	Restart  []string // pris en compte au prochain démarrage
+}
+
+func (p ReloadPlan) Empty() bool {
+	return len(p.Live) == 0 && len(p.Triggers) == 0 && len(p.Restart) == 0
+}
+
+func PlanReload(current, next *Config) ReloadPlan {
+	var plan ReloadPlan
+	for _, field := range changedFields(reflect.ValueOf(*current), reflect.ValueOf(*next), "") {
+		switch {
+		case matchField(field, liveFields):
+			plan.Live = append(plan.Live, field)
+		case matchField(field, triggerFields):
+			plan.Triggers = append(plan.Triggers, field)
+		default:
+			plan.Restart = append(plan.Restart, field)
+		}
+	}
+	return plan
+}
+
+// WithLive renvoie une copie de c dans laquelle seuls les champs modifiables
+// à chaud ont été repris de next.
+func (c *Config) WithLive(next *Config) *Config {
+	merged := *c
+	merged.Webhook = next.Webhook
+	merged.Logging.Level = next.Logging.Level
+	merged.Logging.Language = next.Logging.Language
+	merged.Worker.PoolSize = next.Worker.PoolSize
+	return &merged
+}
+
+func matchField(field string, patterns []string) bool {
+	for _, p := range patterns {
+		if field == p || (strings.HasSuffix(p, ".") && strings.HasPrefix(field, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func changedFields(a, b reflect.Value, path string) []string {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return []string{path}
+		}
+		return nil
+	}
+
+	var fields []string
+	for i := 0; i < a.NumField(); i++ {
+		name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if path != "" {
+			name = fmt.Sprintf("%s.%s", path, name)
+		}
+		fields = append(fields, changedFields(a.Field(i), b.Field(i), name)...)
+	}
+	return fields
+}
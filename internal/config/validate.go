@@ -0,0 +1,578 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/render"
+	"app-db-listener/internal/sqlident"
+)
+
+var (
+	knownModes     = []string{"insert", "update", "delete"}
+	knownLevels    = []string{"debug", "info", "warn", "error"}
+	knownLogFmts   = []string{"text", "json"}
+	knownLogOuts   = []string{"file", "stdout", "stderr", "syslog"}
+	knownPolicies  = []string{"block", "spill_to_disk", "drop"}
+	knownSSLModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	knownDatabases = []string{"postgres", "mysql", "sqlite", "sqlserver"}
+	knownHAModes   = []string{"none", "leader", "shared"}
+	knownFormats   = []string{"json", "cloudevents", "debezium"}
+	knownCEModes   = []string{"structured", "binary"}
+	knownDestTypes = []string{"webhook", "slack", "teams", "discord", "telegram"}
+	knownEventOps  = []string{"INSERT", "UPDATE", "DELETE", "SNAPSHOT", "SCHEMA_CHANGE"}
+	// Valeurs du paramètre encrypt du pilote SQL Server.
+	knownEncrypt = []string{"disable", "false", "true", "strict"}
+)
+
+// ValidationError regroupe tous les problèmes détectés dans la configuration.
+type ValidationError struct {
+	Problems []string
+	lang     string // langue des messages (logging.language)
+}
+
+func (e *ValidationError) Error() string {
+	return i18n.For(e.lang).Sprintf("configuration invalide (%d problème(s)):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Default renvoie la configuration utilisée pour tout champ absent du fichier.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:           "localhost",
+			SSLMode:        "disable",
+			ObjectsSchema:  "paypayo",
+			ManageTriggers: true,
+		},
+		Listener: ListenerConfig{
+			Modes:               "insert,update,delete",
+			PollInterval:        2,
+			MaxPollInterval:     30,
+			BatchSize:           100,
+			SnapshotChunkSize:   1000,
+			SnapshotCheckpoint:  "paypayo-snapshot.json",
+			SchemaCheckInterval: 30,
+		},
+		Webhook: WebhookConfig{
+			Timeout:         10,
+			RetryCount:      3,
+			RetryDelay:      5,
+			Format:          "json",
+			CloudEventsMode: "structured",
+		},
+		Logging: LoggingConfig{
+			File:     "app.log",
+			Level:    "info",
+			Format:   "text",
+			Language: i18n.French,
+			Outputs:  []string{"file"},
+			Rotation: LogRotationConfig{
+				MaxSizeMB:  100,
+				MaxBackups: 10,
+				Compress:   true,
+			},
+			Syslog: SyslogConfig{
+				Tag: "paypayo",
+			},
+		},
+		Worker: WorkerConfig{
+			PoolSize:    5,
+			QueueSize:   100,
+			QueuePolicy: "block",
+			SpillDir:    ".",
+		},
+		Audit: AuditConfig{
+			CleanupInterval: 300,
+			BatchSize:       1000,
+		},
+		HA: HAConfig{
+			Mode:          "none",
+			RetryInterval: 5,
+		},
+		Stream: StreamConfig{
+			BufferSize:   10000,
+			ClientBuffer: 1000,
+			JournalMaxMB: 100,
+		},
+		GRPC: GRPCConfig{
+			PushTimeout:    10,
+			PushRetryCount: 3,
+			PushRetryDelay: 5,
+		},
+	}
+}
+
+// applyTypeDefaults complète les valeurs qui dépendent du type de base.
+func (c *Config) applyTypeDefaults() {
+	if c.Database.Port == 0 {
+		switch c.Database.Type {
+		case "postgres":
+			c.Database.Port = 5432
+		case "mysql":
+			c.Database.Port = 3306
+		case "sqlserver":
+			c.Database.Port = 1433
+		}
+	}
+}
+
+// Validate vérifie l'ensemble de la configuration et renvoie tous les
+// problèmes trouvés dans une seule ValidationError.
+func (c *Config) Validate() error {
+	p := i18n.For(c.Logging.Language)
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, p.Sprintf(format, args...))
+	}
+
+	db := c.Database
+	if !contains(knownDatabases, db.Type) {
+		add("database.type: %q inconnu, valeurs possibles: %s", db.Type, strings.Join(knownDatabases, ", "))
+	}
+	// SQLite: database est le chemin du fichier, sans serveur
+	if db.Type != "sqlite" {
+		if db.Host == "" {
+			add("database.host: obligatoire")
+		}
+		if db.Port < 1 || db.Port > 65535 {
+			add("database.port: %d hors de la plage 1-65535", db.Port)
+		}
+		if db.User == "" {
+			add("database.user: obligatoire")
+		}
+	}
+	if db.Database == "" {
+		add("database.database: obligatoire")
+	}
+	if db.Table == "" {
+		add("database.table: obligatoire")
+	} else if err := validateTableName(db.Type, db.Table); err != nil {
+		add("database.table: %v", err)
+	} else if table, _ := sqlident.ParseName(db.Table); table.Schema != "" && db.Schema != "" && table.Schema != db.Schema {
+		add("database.schema: %q contredit le schéma de database.table %q", db.Schema, db.Table)
+	}
+	if db.Schema != "" {
+		if err := dialectFor(db.Type).Validate(db.Schema); err != nil {
+			add("database.schema: %v", err)
+		}
+	}
+	if db.Type == "postgres" {
+		if err := dialectFor(db.Type).Validate(db.ObjectsSchema); err != nil {
+			add("database.objects_schema: %v", err)
+		}
+	}
+	if db.Type == "postgres" && !contains(knownSSLModes, db.SSLMode) {
+		add("database.sslmode: %q inconnu, valeurs possibles: %s", db.SSLMode, strings.Join(knownSSLModes, ", "))
+	}
+	if db.Type == "sqlserver" && !contains(knownEncrypt, db.SSLMode) {
+		add("database.sslmode: %q inconnu pour sqlserver, valeurs possibles: %s", db.SSLMode, strings.Join(knownEncrypt, ", "))
+	}
+	if db.Type == "sqlite" {
+		// Les triggers SQLite ne peuvent viser que le fichier de leur table
+		if table, err := sqlident.ParseName(db.Table); (err == nil && table.Schema != "") || db.Schema != "" {
+			add("database.table: schéma non supporté avec sqlite, indiquez le nom seul")
+		}
+		if c.HA.Mode == "leader" {
+			add("ha.mode: leader non disponible avec sqlite (base locale, instance unique)")
+		}
+		if c.Audit.Archive || c.Audit.PartitionByDay {
+			add("audit.archive et audit.partition_by_day: non disponibles avec sqlite")
+		}
+	}
+
+	modes := c.Listener.ModeList()
+	if len(modes) == 0 {
+		add("listener.modes: au moins un mode parmi %s est requis", strings.Join(knownModes, ", "))
+	}
+	for _, mode := range modes {
+		if !contains(knownModes, mode) {
+			add("listener.modes: mode %q inconnu, valeurs possibles: %s", mode, strings.Join(knownModes, ", "))
+		}
+	}
+	if c.Listener.PollInterval < 1 {
+		add("listener.poll_interval: %d, doit être d'au moins 1 seconde", c.Listener.PollInterval)
+	}
+	if c.Listener.MaxPollInterval < c.Listener.PollInterval {
+		add("listener.max_poll_interval: %d, doit être supérieur ou égal à poll_interval (%d)", c.Listener.MaxPollInterval, c.Listener.PollInterval)
+	}
+	if c.Listener.BatchSize < 1 {
+		add("listener.batch_size: %d, doit être positif", c.Listener.BatchSize)
+	}
+	if c.Listener.SnapshotChunkSize < 1 {
+		add("listener.snapshot_chunk_size: %d, doit être positif", c.Listener.SnapshotChunkSize)
+	}
+	if c.Listener.SnapshotCheckpoint == "" {
+		add("listener.snapshot_checkpoint: obligatoire")
+	}
+	if c.Listener.SchemaCheckInterval < 0 {
+		add("listener.schema_check_interval: %d, ne peut pas être négatif", c.Listener.SchemaCheckInterval)
+	}
+
+	// Sans destinations, webhook.url peut manquer si les événements ne vont
+	// qu'aux abonnés gRPC ou HTTP; renseignée ou utilisée par une
+	// destination, elle est toujours vérifiée.
+	sinksOnly := len(c.Webhook.Destinations) == 0 && (c.GRPC.Enabled() || c.HTTP.Addr != "")
+	if c.Webhook.URL != "" || c.Webhook.needsURL() && !sinksOnly {
+		if err := validateURL(c.Webhook.URL); err != nil {
+			add("webhook.url: %v", err)
+		}
+	}
+	if c.Webhook.Timeout < 1 {
+		add("webhook.timeout: %d, doit être d'au moins 1 seconde", c.Webhook.Timeout)
+	}
+	if c.Webhook.RetryCount < 0 {
+		add("webhook.retry_count: %d, ne peut pas être négatif", c.Webhook.RetryCount)
+	}
+	if c.Webhook.RetryDelay < 0 {
+		add("webhook.retry_delay: %d, ne peut pas être négatif", c.Webhook.RetryDelay)
+	}
+	if !contains(knownFormats, c.Webhook.Format) {
+		add("webhook.format: %q inconnu, valeurs possibles: %s", c.Webhook.Format, strings.Join(knownFormats, ", "))
+	}
+	if !contains(knownCEModes, c.Webhook.CloudEventsMode) {
+		add("webhook.cloudevents_mode: %q inconnu, valeurs possibles: %s", c.Webhook.CloudEventsMode, strings.Join(knownCEModes, ", "))
+	}
+	if c.Webhook.CloudEventsSource != "" {
+		if _, err := url.Parse(c.Webhook.CloudEventsSource); err != nil {
+			add("webhook.cloudevents_source: %q invalide: %v", c.Webhook.CloudEventsSource, err)
+		}
+	}
+	problems = append(problems, c.Webhook.validateDestinations(p)...)
+
+	if len(c.Logging.Outputs) == 0 {
+		add("logging.outputs: au moins une sortie requise (%s)", strings.Join(knownLogOuts, ", "))
+	}
+	for _, o := range c.Logging.Outputs {
+		if !contains(knownLogOuts, o) {
+			add("logging.outputs: %q inconnue, valeurs possibles: %s", o, strings.Join(knownLogOuts, ", "))
+		}
+	}
+	if c.Logging.HasOutput("file") && c.Logging.File == "" {
+		add("logging.file: obligatoire avec la sortie file")
+	}
+	if r := c.Logging.Rotation; r.MaxSizeMB < 0 || r.IntervalHours < 0 || r.MaxBackups < 0 || r.MaxAgeDays < 0 {
+		add("logging.rotation: les valeurs doivent être positives ou nulles")
+	}
+	if c.Logging.HasOutput("syslog") {
+		switch c.Logging.Syslog.Network {
+		case "":
+		case "udp", "tcp":
+			if c.Logging.Syslog.Address == "" {
+				add("logging.syslog.address: obligatoire avec network %s", c.Logging.Syslog.Network)
+			}
+		default:
+			add("logging.syslog.network: %q inconnu, valeurs possibles: udp, tcp (vide pour le syslog local)", c.Logging.Syslog.Network)
+		}
+	}
+	if !contains(knownLevels, c.Logging.Level) {
+		add("logging.level: %q inconnu, valeurs possibles: %s", c.Logging.Level, strings.Join(knownLevels, ", "))
+	}
+	if !contains(knownLogFmts, c.Logging.Format) {
+		add("logging.format: %q inconnu, valeurs possibles: %s", c.Logging.Format, strings.Join(knownLogFmts, ", "))
+	}
+	if !contains(i18n.Languages, c.Logging.Language) {
+		add("logging.language: %q inconnue, valeurs possibles: %s", c.Logging.Language, strings.Join(i18n.Languages, ", "))
+	}
+
+	if c.Worker.PoolSize < 1 {
+		add("worker.pool_size: %d, au moins 1 worker est requis", c.Worker.PoolSize)
+	}
+	if c.Worker.QueueSize < 1 {
+		add("worker.queue_size: %d, doit être positif", c.Worker.QueueSize)
+	}
+	if !contains(knownPolicies, c.Worker.QueuePolicy) {
+		add("worker.queue_policy: %q inconnue, valeurs possibles: %s", c.Worker.QueuePolicy, strings.Join(knownPolicies, ", "))
+	}
+	if c.Worker.QueuePolicy == "spill_to_disk" && c.Worker.SpillDir == "" {
+		add("worker.spill_dir: obligatoire avec queue_policy spill_to_disk")
+	}
+
+	if c.Audit.RetentionHours < 0 {
+		add("audit.retention_hours: %d, ne peut pas être négatif", c.Audit.RetentionHours)
+	}
+	if c.Audit.CleanupInterval < 1 {
+		add("audit.cleanup_interval: %d, doit être d'au moins 1 seconde", c.Audit.CleanupInterval)
+	}
+	if c.Audit.BatchSize < 1 {
+		add("audit.batch_size: %d, doit être positif", c.Audit.BatchSize)
+	}
+
+	if !contains(knownHAModes, c.HA.Mode) {
+		add("ha.mode: %q inconnu, valeurs possibles: %s", c.HA.Mode, strings.Join(knownHAModes, ", "))
+	}
+	if c.HA.Mode == "shared" && db.Type != "mysql" {
+		add("ha.mode: shared n'est disponible qu'avec MySQL (utilisez leader)")
+	}
+	if c.HA.RetryInterval < 1 {
+		add("ha.retry_interval: %d, doit être d'au moins 1 seconde", c.HA.RetryInterval)
+	}
+
+	if c.Stream.BufferSize < 1 {
+		add("stream.buffer_size: %d, doit être positif", c.Stream.BufferSize)
+	}
+	if c.Stream.ClientBuffer < 1 {
+		add("stream.client_buffer: %d, doit être positif", c.Stream.ClientBuffer)
+	}
+	if c.Stream.JournalMaxMB < 1 {
+		add("stream.journal_max_mb: %d, doit être positif", c.Stream.JournalMaxMB)
+	}
+
+	if c.GRPC.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.GRPC.Addr); err != nil {
+			add("grpc.addr: %q invalide, format attendu hôte:port", c.GRPC.Addr)
+		}
+	}
+	if (c.GRPC.TLSCert == "") != (c.GRPC.TLSKey == "") {
+		add("grpc.tls_cert et grpc.tls_key: doivent être renseignés ensemble")
+	}
+	if c.GRPC.PushTarget != "" {
+		if c.GRPC.PushTimeout < 1 {
+			add("grpc.push_timeout: %d, doit être d'au moins 1 seconde", c.GRPC.PushTimeout)
+		}
+		if c.GRPC.PushRetryCount < 0 {
+			add("grpc.push_retry_count: %d, ne peut pas être négatif", c.GRPC.PushRetryCount)
+		}
+		if c.GRPC.PushRetryDelay < 0 {
+			add("grpc.push_retry_delay: %d, ne peut pas être négatif", c.GRPC.PushRetryDelay)
+		}
+	}
+
+	if c.HTTP.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+			add("http.addr: %q invalide, format attendu hôte:port", c.HTTP.Addr)
+		}
+	}
+	if (c.HTTP.TLSCert == "") != (c.HTTP.TLSKey == "") {
+		add("http.tls_cert et http.tls_key: doivent être renseignés ensemble")
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			add("metrics.addr: %q invalide, format attendu hôte:port", c.Metrics.Addr)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems, lang: c.Logging.Language}
+	}
+	return nil
+}
+
+// needsURL indique si webhook.url est utilisée: sans destinations, ou par
+// une destination qui n'a pas sa propre url.
+func (w *WebhookConfig) needsURL() bool {
+	if len(w.Destinations) == 0 {
+		return true
+	}
+	for _, d := range w.Destinations {
+		if d.URL == "" && !d.IsChat() {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDestinations vérifie les urls et compile chaque template sur un
+// événement fictif.
+func (w *WebhookConfig) validateDestinations(p i18n.Printer) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, p.Sprintf(format, args...))
+	}
+
+	names := make(map[string]bool)
+	for i, d := range w.Destinations {
+		path := fmt.Sprintf("webhook.destinations[%d]", i)
+		if d.Name != "" {
+			if names[d.Name] {
+				add("%s.name: %q déjà utilisé", path, d.Name)
+			}
+			names[d.Name] = true
+		}
+		if d.Type != "" && !contains(knownDestTypes, d.Type) {
+			add("%s.type: %q inconnu, valeurs possibles: %s", path, d.Type, strings.Join(knownDestTypes, ", "))
+		}
+		if d.URL != "" {
+			if err := validateURL(d.URL); err != nil {
+				add("%s.url: %v", path, err)
+			}
+		} else if d.IsChat() && d.Type != "telegram" {
+			add("%s.url: obligatoire pour le type %s (url du webhook entrant)", path, d.Type)
+		}
+		if d.Type == "telegram" && (d.Token == "" || d.ChatID == "") {
+			add("%s: token et chat_id sont obligatoires pour le type telegram", path)
+		}
+		if d.IsChat() && d.Body != "" {
+			add("%s.body: non utilisé par le type %s, utilisez message", path, d.Type)
+		}
+		if !d.IsChat() && d.Message != "" {
+			add("%s.message: réservé aux types slack, teams, discord et telegram", path)
+		}
+		for _, op := range d.Operations {
+			if !contains(knownEventOps, strings.ToUpper(op)) {
+				add("%s.operations: %q inconnue, valeurs possibles: %s", path, op, strings.Join(knownEventOps, ", "))
+			}
+		}
+
+		templates := map[string]string{"path": d.Path, "query": d.Query, "body": d.Body, "message": d.Message, "when": d.When}
+		for name, value := range d.Headers {
+			templates["headers."+name] = value
+		}
+		for _, field := range sortedKeys(templates) {
+			if templates[field] == "" {
+				continue
+			}
+			if err := render.Check(field, templates[field]); err != nil {
+				add("%s.%s: template invalide: %v", path, field, err)
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func dialectFor(dbType string) sqlident.Dialect {
+	switch dbType {
+	case "mysql":
+		return sqlident.MySQL
+	case "sqlite":
+		return sqlident.SQLite
+	case "sqlserver":
+		return sqlident.SQLServer
+	}
+	return sqlident.Postgres
+}
+
+func validateTableName(dbType, name string) error {
+	table, err := sqlident.ParseName(name)
+	if err != nil {
+		return err
+	}
+	return table.Validate(dialectFor(dbType))
+}
+
+func validateURL(raw string) error {
+	if raw == "" {
+		return i18n.Errorf("obligatoire")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return i18n.Errorf("%q invalide: %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return i18n.Errorf("%q: schéma http ou https attendu", raw)
+	}
+	if u.Host == "" {
+		return i18n.Errorf("%q: hôte manquant", raw)
+	}
+	return nil
+}
+
+// checkKnownFields signale les clés YAML qui ne correspondent à aucun champ,
+// avec une suggestion quand une clé proche existe.
+func checkKnownFields(node *yaml.Node, t reflect.Type, path string, p i18n.Printer) []string {
+	if node.Kind == yaml.DocumentNode {
+		var problems []string
+		for _, child := range node.Content {
+			problems = append(problems, checkKnownFields(child, t, path, p)...)
+		}
+		return problems
+	}
+	if node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice {
+		var problems []string
+		for i, child := range node.Content {
+			problems = append(problems, checkKnownFields(child, t.Elem(), fmt.Sprintf("%s[%d]", path, i), p)...)
+		}
+		return problems
+	}
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = t.Field(i).Type
+		names = append(names, name)
+	}
+
+	var problems []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		full := key.Value
+		if path != "" {
+			full = path + "." + key.Value
+		}
+
+		ft, ok := fields[key.Value]
+		if !ok {
+			msg := p.Sprintf("ligne %d: champ inconnu %s", key.Line, full)
+			if suggestion := closest(key.Value, names); suggestion != "" {
+				msg += p.Sprintf(" (vouliez-vous dire %s ?)", suggestion)
+			}
+			problems = append(problems, msg)
+			continue
+		}
+
+		problems = append(problems, checkKnownFields(value, ft, full, p)...)
+	}
+	return problems
+}
+
+func closest(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := levenshtein(name, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+)
+
+var errLockLost = i18n.NewError("verrou non détenu")
+
+// lockName identifie la table surveillée entre les instances.
+func lockName(cfg *config.Config) string {
+	if cfg.HA.LockName != "" {
+		return cfg.HA.LockName
+	}
+	return fmt.Sprintf("paypayo:%s.%s", cfg.Database.Database, cfg.Database.Table)
+}
+
+// dbLock est un verrou applicatif détenu par une connexion dédiée: verrou
+// consultatif PostgreSQL, GET_LOCK MySQL ou sp_getapplock SQL Server. La base
+// le libère d'elle-même si la connexion est perdue.
+type dbLock struct {
+	mu      sync.Mutex
+	db      *sql.DB
+	conn    *sql.Conn
+	name    string
+	acquire string
+	release string
+}
+
+func newDBLock(cfg *config.Config, name string) (*dbLock, error) {
+	l := &dbLock{name: name}
+
+	var err error
+	switch cfg.Database.Type {
+	case "postgres":
+		l.db, err = sql.Open("postgres", postgresConnString(cfg))
+		l.acquire = "SELECT pg_try_advisory_lock(hashtext($1))::int"
+		l.release = "SELECT pg_advisory_unlock(hashtext($1))"
+	case "mysql":
+		// GET_LOCK limite les noms à 64 caractères
+		l.name = my.Shorten(name)
+		l.db, err = sql.Open("mysql", mysqlDSN(cfg))
+		l.acquire = "SELECT GET_LOCK(?, 0)"
+		l.release = "SELECT RELEASE_LOCK(?)"
+	case "sqlserver":
+		// Verrou de session, libéré avec la connexion
+		l.db, err = sql.Open("sqlserver", sqlserverDSN(cfg))
+		l.acquire = `DECLARE @r INT;
+			EXEC @r = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+			SELECT CASE WHEN @r >= 0 THEN 1 ELSE 0 END`
+		l.release = "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'"
+	default:
+		return nil, i18n.Errorf("type de base de données non supporté: %s", cfg.Database.Type)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("erreur connexion verrou: %w", err)
+	}
+
+	return l, nil
+}
+
+// tryAcquire prend le verrou sans attendre et renvoie false s'il est détenu
+// par une autre instance.
+func (l *dbLock) tryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		conn, err := l.db.Conn(ctx)
+		if err != nil {
+			return false, err
+		}
+		l.conn = conn
+	}
+
+	var got sql.NullInt64
+	if err := l.conn.QueryRowContext(ctx, l.acquire, l.name).Scan(&got); err != nil {
+		l.drop()
+		return false, err
+	}
+	return got.Int64 == 1, nil
+}
+
+// check vérifie que la connexion qui détient le verrou est toujours ouverte.
+func (l *dbLock) check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return errLockLost
+	}
+	if _, err := l.conn.ExecContext(ctx, "SELECT 1"); err != nil {
+		l.drop()
+		return err
+	}
+	return nil
+}
+
+func (l *dbLock) held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn != nil
+}
+
+// unlock libère le verrou pour qu'une autre instance puisse le prendre.
+func (l *dbLock) unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		l.conn.ExecContext(context.Background(), l.release, l.name)
+		l.drop()
+	}
+}
+
+func (l *dbLock) drop() {
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+func (l *dbLock) Close() error {
+	l.unlock()
+	return l.db.Close()
+}
+
+// leaderListener n'écoute que lorsqu'il détient le verrou de la table
+// (mode actif/passif). Les objets sont installés par l'instance active
+// uniquement; si le verrou est perdu, l'écoute s'arrête et l'instance
+// redevient passive.
+type leaderListener struct {
+	config   *config.Config
+	logger   *logger.Logger
+	notifier *notifier.Notifier
+	lock     *dbLock
+
+	mu       sync.Mutex
+	inner    Listener
+	poolSize int
+	ready    chan struct{}
+	once     sync.Once
+}
+
+func newLeaderListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (*leaderListener, error) {
+	lock, err := newDBLock(cfg, lockName(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return &leaderListener{
+		config:   cfg,
+		logger:   log,
+		notifier: ntf,
+		lock:     lock,
+		poolSize: cfg.Worker.PoolSize,
+		ready:    make(chan struct{}),
+	}, nil
+}
+
+func (l *leaderListener) Listen(ctx context.Context) error {
+	retry := time.Duration(l.config.HA.RetryInterval) * time.Second
+
+	for {
+		if err := l.awaitLeadership(ctx, retry); err != nil {
+			return err
+		}
+
+		err := l.lead(ctx, retry)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		l.logger.Error("Instance active arrêtée: %v; retour en attente du verrou", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (l *leaderListener) awaitLeadership(ctx context.Context, retry time.Duration) error {
+	waiting := false
+	for {
+		ok, err := l.lock.tryAcquire(ctx)
+		switch {
+		case err != nil:
+			l.logger.Warn("Erreur prise du verrou %s: %v", l.lock.name, err)
+		case ok:
+			l.logger.Info("Verrou %s obtenu, instance active", l.lock.name)
+			return nil
+		case !waiting:
+			l.logger.Info("Verrou %s détenu par une autre instance, instance passive en attente", l.lock.name)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// lead crée le listener (installation des objets comprise) et écoute tant que
+// le verrou est détenu.
+func (l *leaderListener) lead(ctx context.Context, retry time.Duration) error {
+	l.mu.Lock()
+	cfg := *l.config
+	cfg.Worker.PoolSize = l.poolSize
+	l.mu.Unlock()
+
+	inner, err := newListener(&cfg, l.logger, l.notifier)
+	if err != nil {
+		l.lock.unlock()
+		return err
+	}
+
+	l.mu.Lock()
+	l.inner = inner
+	l.mu.Unlock()
+	l.once.Do(func() { close(l.ready) })
+
+	defer func() {
+		l.mu.Lock()
+		l.inner = nil
+		l.mu.Unlock()
+		inner.Close()
+	}()
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- inner.Listen(leadCtx)
+	}()
+
+	ticker := time.NewTicker(retry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errCh:
+			l.lock.unlock()
+			return err
+		case <-ticker.C:
+			if err := l.lock.check(ctx); err != nil {
+				cancel()
+				<-errCh
+				return i18n.Errorf("verrou %s perdu: %w", l.lock.name, err)
+			}
+		}
+	}
+}
+
+// Snapshot attend que l'instance soit active.
+func (l *leaderListener) Snapshot(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.ready:
+	}
+
+	l.mu.Lock()
+	inner := l.inner
+	l.mu.Unlock()
+
+	if inner == nil {
+		return i18n.Errorf("instance passive, snapshot impossible")
+	}
+	return inner.Snapshot(ctx)
+}
+
+func (l *leaderListener) SetPoolSize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.poolSize = size
+	if l.inner != nil {
+		l.inner.SetPoolSize(size)
+	}
+}
+
+// Close libère le verrou; le listener actif est fermé par Listen à l'arrêt.
+func (l *leaderListener) Close() error {
+	return l.lock.Close()
+}
@@ -0,0 +1,270 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+)
+
+// objectVersion change quand la forme des objets générés change de manière
+// incompatible; le checksum du DDL détecte les autres différences.
+const objectVersion = "v1"
+
+const markerPrefix = "paypayo:"
+
+type objectState int
+
+const (
+	stateMissing  objectState = iota // absent de la base
+	stateCurrent                     // installé par paypayo, à jour
+	stateOutdated                    // installé par paypayo, DDL différent
+	stateForeign                     // même nom, mais pas créé par paypayo
+)
+
+// dbObject est un objet (schéma, fonction, table, trigger) géré par paypayo.
+type dbObject struct {
+	kind    string
+	name    string
+	create  []string
+	drop    string
+	enabled bool
+	// shared: l'objet peut exister sans marqueur (ex: schéma créé par un DBA),
+	// il n'est alors ni modifié ni supprimé.
+	shared bool
+	// preserve: l'objet contient des données; une version différente est
+	// signalée mais jamais recréée.
+	preserve bool
+}
+
+func (o dbObject) String() string {
+	return fmt.Sprintf("%s %s", o.kind, o.name)
+}
+
+// marker identifie un objet créé par paypayo et la version de son DDL.
+func (o dbObject) marker() string {
+	sum := sha256.Sum256([]byte(strings.Join(o.create, ";\n")))
+	return markerPrefix + objectVersion + ":" + hex.EncodeToString(sum[:6])
+}
+
+func markerState(found bool, marker, expected string) objectState {
+	switch {
+	case !found:
+		return stateMissing
+	case marker == expected:
+		return stateCurrent
+	case strings.HasPrefix(marker, markerPrefix):
+		return stateOutdated
+	default:
+		return stateForeign
+	}
+}
+
+// objectManager est implémenté par chaque backend. script renvoie les
+// requêtes qui créent l'objet et posent son marqueur.
+type objectManager interface {
+	plan(ctx context.Context) ([]dbObject, error)
+	state(ctx context.Context, obj dbObject) (objectState, error)
+	script(obj dbObject) []string
+	apply(ctx context.Context, obj dbObject, replace bool) error
+	remove(ctx context.Context, obj dbObject) error
+}
+
+// Installer gère explicitement les objets paypayo (commandes install,
+// uninstall et generate-sql).
+type Installer interface {
+	Install(ctx context.Context, adopt bool) error
+	Uninstall(ctx context.Context) error
+	Verify(ctx context.Context) error
+	GenerateSQL(ctx context.Context, w io.Writer) error
+	Close() error
+}
+
+func NewInstaller(cfg *config.Config, log *logger.Logger) (Installer, error) {
+	switch cfg.Database.Type {
+	case "postgres":
+		return openPostgresObjects(cfg, log)
+	case "mysql":
+		return openMySQLObjects(cfg, log)
+	case "sqlite":
+		return openSQLiteObjects(cfg, log)
+	case "sqlserver":
+		return openSQLServerObjects(cfg, log)
+	default:
+		return nil, i18n.Errorf("type de base de données non supporté: %s", cfg.Database.Type)
+	}
+}
+
+// installObjects crée les objets manquants, met à jour ceux de paypayo qui
+// sont périmés et supprime les triggers paypayo des modes désactivés. Un objet
+// de même nom qui n'appartient pas à paypayo n'est jamais modifié, sauf avec
+// adopt pour reprendre une installation antérieure aux marqueurs.
+func installObjects(ctx context.Context, m objectManager, log *logger.Logger, adopt bool) error {
+	objects, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		st, err := m.state(ctx, obj)
+		if err != nil {
+			return i18n.Errorf("erreur lecture état %s: %w", obj, err)
+		}
+
+		// Un objet désactivé n'est jamais créé: un homonyme étranger est ignoré.
+		if !obj.enabled {
+			if st == stateCurrent || st == stateOutdated {
+				if err := m.remove(ctx, obj); err != nil {
+					return i18n.Errorf("erreur suppression %s: %w", obj, err)
+				}
+				log.Info("%s supprimé (mode désactivé)", obj)
+			}
+			continue
+		}
+
+		if st == stateForeign && obj.shared {
+			log.Debug("%s existe déjà, utilisé tel quel", obj)
+			continue
+		}
+		if st == stateForeign && !adopt {
+			return i18n.Errorf("%s existe déjà et n'a pas été créé par paypayo; renommez-le, supprimez-le ou lancez 'paypayo install -adopt' pour le remplacer", obj)
+		}
+
+		switch st {
+		case stateCurrent:
+			log.Debug("%s à jour", obj)
+		case stateMissing:
+			if err := m.apply(ctx, obj, false); err != nil {
+				return i18n.Errorf("erreur création %s: %w", obj, err)
+			}
+			log.Info("%s créé", obj)
+		case stateOutdated, stateForeign:
+			if obj.preserve && st == stateOutdated {
+				log.Warn("%s a une structure différente de la version attendue; conservé tel quel", obj)
+				continue
+			}
+			if err := m.apply(ctx, obj, true); err != nil {
+				return i18n.Errorf("erreur mise à jour %s: %w", obj, err)
+			}
+			log.Info("%s mis à jour", obj)
+		}
+	}
+
+	return nil
+}
+
+// uninstallObjects supprime, dans l'ordre inverse de création, les objets
+// marqués comme appartenant à paypayo.
+func uninstallObjects(ctx context.Context, m objectManager, log *logger.Logger) error {
+	objects, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+
+		st, err := m.state(ctx, obj)
+		if err != nil {
+			return i18n.Errorf("erreur lecture état %s: %w", obj, err)
+		}
+
+		switch st {
+		case stateMissing:
+			continue
+		case stateForeign:
+			if !obj.shared {
+				log.Warn("%s n'a pas été créé par paypayo, conservé", obj)
+			}
+			continue
+		}
+
+		if err := m.remove(ctx, obj); err != nil {
+			if obj.shared {
+				log.Warn("%s conservé: %v", obj, err)
+				continue
+			}
+			return i18n.Errorf("erreur suppression %s: %w", obj, err)
+		}
+		log.Info("%s supprimé", obj)
+	}
+
+	return nil
+}
+
+// verifyObjects contrôle, sans rien modifier, que les objets attendus existent
+// et sont à jour. Utilisé quand manage_triggers est désactivé.
+func verifyObjects(ctx context.Context, m objectManager, log *logger.Logger) error {
+	objects, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, obj := range objects {
+		st, err := m.state(ctx, obj)
+		if err != nil {
+			return i18n.Errorf("erreur lecture état %s: %w", obj, err)
+		}
+
+		if !obj.enabled {
+			if st == stateCurrent || st == stateOutdated {
+				log.Warn("%s existe alors que le mode est désactivé; il peut être supprimé", obj)
+			}
+			continue
+		}
+
+		switch {
+		case st == stateCurrent, st == stateForeign && obj.shared:
+			log.Debug("%s à jour", obj)
+		case st == stateOutdated && obj.preserve:
+			log.Warn("%s a une structure différente de la version attendue; conservé tel quel", obj)
+		case st == stateMissing:
+			problems = append(problems, i18n.Sprintf("%s absent", obj))
+		case st == stateOutdated:
+			problems = append(problems, i18n.Sprintf("%s n'est pas à jour", obj))
+		default:
+			problems = append(problems, i18n.Sprintf("%s n'a pas été créé par paypayo", obj))
+		}
+	}
+
+	if len(problems) > 0 {
+		return i18n.Errorf("objets paypayo manquants ou périmés (manage_triggers désactivé), appliquez le SQL de 'paypayo generate-sql':\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// writeScript écrit les requêtes des objets activés, séparées par des « ; »,
+// dans l'ordre où install les applique.
+func writeScript(ctx context.Context, w io.Writer, m objectManager, header string, preamble []string) error {
+	objects, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	i18n.Fprintf(w, "-- %s\n-- Généré par 'paypayo generate-sql', à appliquer avec un utilisateur disposant des droits DDL.\n", header)
+
+	if len(preamble) > 0 {
+		fmt.Fprintln(w)
+		for _, stmt := range preamble {
+			fmt.Fprintf(w, "%s;\n", strings.TrimSpace(stmt))
+		}
+	}
+
+	for _, obj := range objects {
+		if !obj.enabled {
+			continue
+		}
+		fmt.Fprintf(w, "\n-- %s\n", obj)
+		for _, stmt := range m.script(obj) {
+			fmt.Fprintf(w, "%s;\n", strings.TrimSpace(stmt))
+		}
+	}
+	return nil
+}
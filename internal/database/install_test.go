@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/sqlident"
+)
+
+func ddlConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Database.ObjectsSchema = "paypayo"
+	cfg.Listener.Modes = "insert,update,delete"
+	cfg.Audit.Archive = true
+	return cfg
+}
+
+// ddl concatène le DDL de création de tous les objets.
+func ddl(objects []dbObject) string {
+	var b strings.Builder
+	for _, obj := range objects {
+		for _, stmt := range obj.create {
+			b.WriteString(stmt)
+			b.WriteString(";\n")
+		}
+		b.WriteString(obj.drop)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+func TestPostgresPlanDDL(t *testing.T) {
+	long := strings.Repeat("t", 60)
+
+	tests := []struct {
+		name  string
+		table sqlident.Name
+		want  []string
+	}{
+		{
+			name:  "simple",
+			table: sqlident.Name{Schema: "public", Name: "users"},
+			want: []string{
+				`CREATE OR REPLACE FUNCTION "paypayo"."notify_public_users"()`,
+				`PERFORM pg_notify('paypayo_public_users', payload::text)`,
+				`CREATE TRIGGER "users_insert_trigger"`,
+				`AFTER UPDATE ON "public"."users"`,
+				`EXECUTE FUNCTION "paypayo"."notify_public_users"()`,
+				`DROP TRIGGER "users_delete_trigger" ON "public"."users"`,
+			},
+		},
+		{
+			name:  "guillemets",
+			table: sqlident.Name{Schema: `Bill"ing`, Name: `pay"ments`},
+			want: []string{
+				`ON "Bill""ing"."pay""ments"`,
+				`CREATE TRIGGER "pay""ments_insert_trigger"`,
+				`"paypayo"."notify_Bill""ing_pay""ments"`,
+			},
+		},
+		{
+			name:  "apostrophe",
+			table: sqlident.Name{Schema: "public", Name: "o'brien"},
+			want: []string{
+				`pg_notify('paypayo_public_o''brien', payload::text)`,
+				`ON "public"."o'brien"`,
+			},
+		},
+		{
+			name:  "mot réservé",
+			table: sqlident.Name{Schema: "select", Name: "order"},
+			want: []string{
+				`AFTER INSERT ON "select"."order"`,
+				`CREATE TRIGGER "order_insert_trigger"`,
+			},
+		},
+		{
+			name:  "nom long",
+			table: sqlident.Name{Schema: "public", Name: long},
+			want: []string{
+				`AFTER INSERT ON "public"."` + long + `"`,
+				`CREATE TRIGGER "` + pg.Shorten(long+"_insert_trigger") + `"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &pgObjects{table: tt.table, config: ddlConfig()}
+			objects, err := o.plan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := ddl(objects)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("DDL sans %s:\n%s", want, out)
+				}
+			}
+
+			for _, name := range []string{o.channelName(), o.functionName().Name, o.triggerName("insert"), o.triggerName("update"), o.triggerName("delete")} {
+				if err := pg.Validate(name); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMySQLPlanDDL(t *testing.T) {
+	long := strings.Repeat("t", 64)
+	columns := []column{
+		{name: "id", columnType: "int", dataType: "int"},
+		{name: "order", columnType: "int", dataType: "int"},
+		{name: "o'brien", columnType: "varchar(10)", dataType: "varchar"},
+		{name: "we`ird", columnType: "varchar(10)", dataType: "varchar"},
+		{name: `back\slash`, columnType: "varchar(10)", dataType: "varchar"},
+	}
+
+	tests := []struct {
+		name   string
+		table  sqlident.Name
+		server mysqlServer
+		want   []string
+	}{
+		{
+			name:  "simple",
+			table: sqlident.Name{Schema: "shop", Name: "users"},
+			want: []string{
+				"CREATE TABLE IF NOT EXISTS `shop`.`users_audit`",
+				"CREATE TABLE IF NOT EXISTS `shop`.`users_audit_archive` LIKE `shop`.`users_audit`",
+				"CREATE TRIGGER `shop`.`users_insert_trigger`",
+				"AFTER UPDATE ON `shop`.`users`",
+				"INSERT INTO `shop`.`users_audit` (operation, table_name, data, old_data)",
+				"VALUES ('DELETE', 'shop.users', JSON_OBJECT(",
+			},
+		},
+		{
+			name:  "colonnes",
+			table: sqlident.Name{Name: "users"},
+			want: []string{
+				"'id', NEW.`id`",
+				"'order', NEW.`order`",
+				"'o''brien', NEW.`o'brien`",
+				"'we`ird', OLD.`we``ird`",
+				"CONCAT('back', CHAR(92 USING utf8mb4), 'slash'), NEW.`back\\slash`",
+			},
+		},
+		{
+			name:  "backtick",
+			table: sqlident.Name{Schema: "my`db", Name: "a`b"},
+			want: []string{
+				"AFTER INSERT ON `my``db`.`a``b`",
+				"CREATE TABLE IF NOT EXISTS `my``db`.`a``b_audit`",
+				"CREATE TRIGGER `my``db`.`a``b_insert_trigger`",
+				"DROP TRIGGER `my``db`.`a``b_delete_trigger`",
+			},
+		},
+		{
+			name:  "apostrophe",
+			table: sqlident.Name{Name: "o'brien"},
+			want: []string{
+				"VALUES ('INSERT', 'o''brien', JSON_OBJECT(",
+				"AFTER INSERT ON `o'brien`",
+			},
+		},
+		{
+			name:  "mot réservé",
+			table: sqlident.Name{Schema: "select", Name: "order"},
+			want: []string{
+				"AFTER INSERT ON `select`.`order`",
+				"INSERT INTO `select`.`order_audit`",
+			},
+		},
+		{
+			name:  "nom long",
+			table: sqlident.Name{Name: long},
+			want: []string{
+				"AFTER INSERT ON `" + long + "`",
+				"CREATE TABLE IF NOT EXISTS `" + my.Shorten(long+"_audit") + "`",
+				"CREATE TRIGGER `" + my.Shorten(long+"_update_trigger") + "`",
+			},
+		},
+		{
+			name:   "MariaDB",
+			table:  sqlident.Name{Name: "users"},
+			server: mysqlServer{flavor: flavorMariaDB},
+			want: []string{
+				"data LONGTEXT",
+				"'id', NEW.`id`",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &mysqlObjects{table: tt.table, server: tt.server, config: ddlConfig()}
+			objects := o.objects(columns)
+
+			out := ddl(objects)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("DDL sans %s:\n%s", want, out)
+				}
+			}
+
+			// Sans NO_BACKSLASH_ESCAPES, un \ du DDL serait un échappement:
+			// il ne doit apparaître que dans un identifiant.
+			for _, obj := range objects {
+				for _, stmt := range obj.create {
+					if strings.Contains(strings.ReplaceAll(stmt, "`back\\slash`", ""), `\`) {
+						t.Errorf("%s: \\ hors identifiant:\n%s", obj, stmt)
+					}
+				}
+			}
+
+			for _, name := range []sqlident.Name{o.derived("_audit"), o.derived("_audit_archive"), o.triggerName("insert"), o.triggerName("update"), o.triggerName("delete")} {
+				if err := name.Validate(my); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		})
+	}
+}
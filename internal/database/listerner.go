@@ -2,25 +2,83 @@ package database
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
 	"app-db-listener/internal/logger"
 	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/sqlident"
 )
 
 type Listener interface {
 	Listen(ctx context.Context) error
+	Snapshot(ctx context.Context) error
+	SetPoolSize(size int)
 	Close() error
 }
 
 func NewListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (Listener, error) {
+	if cfg.HA.Mode == "leader" {
+		return newLeaderListener(cfg, log, ntf)
+	}
+	return newListener(cfg, log, ntf)
+}
+
+func newListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (Listener, error) {
 	switch cfg.Database.Type {
 	case "postgres":
 		return NewPostgresListener(cfg, log, ntf)
 	case "mysql":
 		return NewMySQLListener(cfg, log, ntf)
+	case "sqlite":
+		return NewSQLiteListener(cfg, log, ntf)
+	case "sqlserver":
+		return NewSQLServerListener(cfg, log, ntf)
+	default:
+		return nil, i18n.Errorf("type de base de données non supporté: %s", cfg.Database.Type)
+	}
+}
+
+// eventSource décrit la table surveillée dans les événements.
+func eventSource(cfg *config.Config, table sqlident.Name) notifier.Source {
+	return notifier.Source{
+		Connector: cfg.Database.Type,
+		Database:  cfg.Database.Database,
+		Schema:    table.Schema,
+		Table:     table.Name,
+	}
+}
+
+// stamp attribue un identifiant à l'événement et renseigne son origine.
+func stamp(event *notifier.ChangeEvent, source notifier.Source, position string) {
+	source.Position = position
+	event.ID = notifier.NewID()
+	event.Source = &source
+}
+
+func minPollDelay(cfg *config.ListenerConfig) time.Duration {
+	return time.Duration(cfg.PollInterval) * time.Second
+}
+
+// nextPollDelay renvoie l'attente avant le prochain polling (MySQL, SQLite,
+// SQL Server): aucune si le lot était plein (un arriéré reste à lire),
+// poll_interval si des lignes sont arrivées, et une attente doublée jusqu'à
+// max_poll_interval sinon.
+func nextPollDelay(cfg *config.ListenerConfig, delay time.Duration, n int, err error) time.Duration {
+	min := minPollDelay(cfg)
+	max := time.Duration(cfg.MaxPollInterval) * time.Second
+
+	switch {
+	case err == nil && n >= cfg.BatchSize:
+		return 0
+	case err == nil && n > 0:
+		return min
+	case delay < min:
+		return min
+	case delay*2 > max:
+		return max
 	default:
-		return nil, fmt.Errorf("type de base de données non supporté: %s", cfg.Database.Type)
+		return delay * 2
 	}
 }
@@ -3,219 +3,205 @@ package database
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
 	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
 	"app-db-listener/internal/logger"
 	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+	"app-db-listener/internal/sqlident"
 )
 
+const my = sqlident.MySQL
+
 type MySQLListener struct {
-	db       *sql.DB
-	config   *config.Config
-	logger   *logger.Logger
+	*mysqlObjects
 	notifier *notifier.Notifier
-	eventCh  chan *notifier.ChangeEvent
+	queue    *queue.Queue
+	gate     *snapshotGate
+	workers  *workerPool
+	// Colonnes lues au démarrage ou au dernier changement détecté.
+	schema      []column
+	fingerprint string
+	types       atomic.Pointer[tableTypes]
+	// Dernière id lue par le polling (high-water mark).
+	lastID int64
+	// En mode shared, une seule instance exécute la maintenance.
+	maintenance *dbLock
+}
+
+// sqlQuerier est satisfait par *sql.DB et *sql.Tx.
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
 func NewMySQLListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (*MySQLListener, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Database,
-	)
-
-	db, err := sql.Open("mysql", dsn)
+	objects, err := openMySQLObjects(cfg, log)
 	if err != nil {
-		return nil, fmt.Errorf("erreur connexion MySQL: %w", err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("erreur ping MySQL: %w", err)
+	switch {
+	case cfg.Database.ManageTriggers && cfg.HA.Mode == "shared":
+		err = objects.sharedInstall(context.Background(), lockName(cfg)+":install")
+	case cfg.Database.ManageTriggers:
+		err = objects.Install(context.Background(), false)
+	default:
+		err = objects.Verify(context.Background())
 	}
-
-	ml := &MySQLListener{
-		db:       db,
-		config:   cfg,
-		logger:   log,
-		notifier: ntf,
-		eventCh:  make(chan *notifier.ChangeEvent, 100),
-	}
-
-	if err := ml.setupAuditTable(); err != nil {
-		return nil, fmt.Errorf("erreur setup audit: %w", err)
-	}
-
-	return ml, nil
-}
-
-func (ml *MySQLListener) setupAuditTable() error {
-	auditTable := fmt.Sprintf("%s_audit", ml.config.Database.Table)
-
-	// Créer la table d'audit
-	createTableSQL := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id BIGINT AUTO_INCREMENT PRIMARY KEY,
-			operation VARCHAR(10) NOT NULL,
-			table_name VARCHAR(255) NOT NULL,
-			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			data JSON,
-			old_data JSON,
-			processed BOOLEAN DEFAULT FALSE,
-			INDEX idx_processed (processed, changed_at)
-		)
-	`, auditTable)
-
-	if _, err := ml.db.Exec(createTableSQL); err != nil {
-		return fmt.Errorf("erreur création table audit: %w", err)
-	}
-
-	ml.logger.Info("Table d'audit créée: %s", auditTable)
-
-	// Supprimer les triggers existants
-	dropTriggers := []string{
-		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_insert_trigger", ml.config.Database.Table),
-		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_update_trigger", ml.config.Database.Table),
-		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_delete_trigger", ml.config.Database.Table),
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur setup audit: %w", err)
 	}
 
-	for _, dropSQL := range dropTriggers {
-		if _, err := ml.db.Exec(dropSQL); err != nil {
-			ml.logger.Warn("Erreur suppression trigger: %v", err)
-		}
+	columns, err := objects.columns(context.Background())
+	if err != nil {
+		objects.Close()
+		return nil, err
 	}
 
-	// Créer les triggers selon la configuration
-	if ml.config.Listener.IsInsertEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_insert_trigger
-			AFTER INSERT ON %s
-			FOR EACH ROW
-			INSERT INTO %s (operation, table_name, data)
-			VALUES ('INSERT', '%s', JSON_OBJECT(%s))
-		`, ml.config.Database.Table, ml.config.Database.Table, auditTable,
-			ml.config.Database.Table, ml.buildColumnList("NEW"))
-
-		if _, err := ml.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger INSERT: %w", err)
-		}
-		ml.logger.Info("Trigger INSERT créé pour la table %s", ml.config.Database.Table)
+	q, err := queue.New(&cfg.Worker, log)
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur création file d'événements: %w", err)
 	}
 
-	if ml.config.Listener.IsUpdateEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_update_trigger
-			AFTER UPDATE ON %s
-			FOR EACH ROW
-			INSERT INTO %s (operation, table_name, data, old_data)
-			VALUES ('UPDATE', '%s', JSON_OBJECT(%s), JSON_OBJECT(%s))
-		`, ml.config.Database.Table, ml.config.Database.Table, auditTable,
-			ml.config.Database.Table, ml.buildColumnList("NEW"), ml.buildColumnList("OLD"))
-
-		if _, err := ml.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger UPDATE: %w", err)
-		}
-		ml.logger.Info("Trigger UPDATE créé pour la table %s", ml.config.Database.Table)
+	ml := &MySQLListener{
+		mysqlObjects: objects,
+		notifier:     ntf,
+		queue:        q,
+		gate:         newSnapshotGate(q),
+		workers:      newWorkerPool(q, ntf, log),
+		schema:       columns,
+		fingerprint:  fingerprint(columns),
 	}
+	ml.types.Store(mysqlTypes(columns))
 
-	if ml.config.Listener.IsDeleteEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_delete_trigger
-			AFTER DELETE ON %s
-			FOR EACH ROW
-			INSERT INTO %s (operation, table_name, data)
-			VALUES ('DELETE', '%s', JSON_OBJECT(%s))
-		`, ml.config.Database.Table, ml.config.Database.Table, auditTable,
-			ml.config.Database.Table, ml.buildColumnList("OLD"))
-
-		if _, err := ml.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger DELETE: %w", err)
+	if cfg.HA.Mode == "shared" {
+		if ml.maintenance, err = newDBLock(cfg, lockName(cfg)+":maintenance"); err != nil {
+			ml.Close()
+			return nil, err
 		}
-		ml.logger.Info("Trigger DELETE créé pour la table %s", ml.config.Database.Table)
 	}
 
-	return nil
+	return ml, nil
 }
 
-func (ml *MySQLListener) buildColumnList(prefix string) string {
-	// Récupérer les colonnes de la table
-	rows, err := ml.db.Query(fmt.Sprintf("DESCRIBE %s", ml.config.Database.Table))
-	if err != nil {
-		ml.logger.Error("Erreur récupération colonnes: %v", err)
-		return ""
+// maintainer indique si cette instance exécute la maintenance (régénération
+// des triggers, nettoyage de l'audit). En mode shared, seule l'instance qui
+// détient le verrou de maintenance s'en charge.
+func (ml *MySQLListener) maintainer(ctx context.Context) bool {
+	if ml.maintenance == nil {
+		return true
 	}
-	defer rows.Close()
-
-	var columns []string
-	for rows.Next() {
-		var field, typ, null, key, def, extra sql.NullString
-		if err := rows.Scan(&field, &typ, &null, &key, &def, &extra); err != nil {
-			continue
-		}
-		columns = append(columns, fmt.Sprintf("'%s', %s.%s", field.String, prefix, field.String))
+	if ml.maintenance.held() && ml.maintenance.check(ctx) == nil {
+		return true
 	}
+	ok, err := ml.maintenance.tryAcquire(ctx)
+	return err == nil && ok
+}
 
-	result := ""
-	for i, col := range columns {
-		if i > 0 {
-			result += ", "
+func mysqlTypes(columns []column) *tableTypes {
+	fields := make([]notifier.Field, len(columns))
+	for i, c := range columns {
+		fields[i] = notifier.Field{
+			Name:   c.name,
+			Type:   mysqlType(c.dataType, c.columnType),
+			DBType: c.columnType,
 		}
-		result += col
 	}
-	return result
+	return newTableTypes(fields)
 }
 
 func (ml *MySQLListener) Listen(ctx context.Context) error {
-	ml.logger.Info("Écoute démarrée sur la table: %s (polling chaque %d secondes)",
-		ml.config.Database.Table, ml.config.Listener.PollInterval)
+	ml.logger.Info("Écoute démarrée sur la table: %s (polling toutes les %d à %d secondes, lots de %d lignes)",
+		ml.config.Database.Table, ml.config.Listener.PollInterval, ml.config.Listener.MaxPollInterval, ml.config.Listener.BatchSize)
 
 	// Démarrer les workers
-	for i := 0; i < ml.config.Worker.PoolSize; i++ {
-		go ml.worker(ctx, i)
-	}
+	ml.workers.start(ctx, ml.config.Worker.PoolSize)
+
+	go ml.runJanitor(ctx)
 
-	ticker := time.NewTicker(time.Duration(ml.config.Listener.PollInterval) * time.Second)
-	defer ticker.Stop()
+	delay := minPollDelay(&ml.config.Listener)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var schemaCheck <-chan time.Time
+	if interval := ml.config.Listener.SchemaCheckInterval; interval > 0 {
+		schemaTicker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer schemaTicker.Stop()
+		schemaCheck = schemaTicker.C
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			ml.logger.Info("Arrêt de l'écoute")
 			return ctx.Err()
-		case <-ticker.C:
-			if err := ml.pollChanges(ctx); err != nil {
+		case <-timer.C:
+			n, err := ml.pollChanges(ctx)
+			if err != nil {
 				ml.logger.Error("Erreur polling: %v", err)
 			}
+			delay = nextPollDelay(&ml.config.Listener, delay, n, err)
+			timer.Reset(delay)
+		case <-schemaCheck:
+			if err := ml.checkSchema(ctx); err != nil {
+				ml.logger.Error("Erreur contrôle du schéma: %v", err)
+			}
 		}
 	}
 }
 
-func (ml *MySQLListener) pollChanges(ctx context.Context) error {
-	auditTable := fmt.Sprintf("%s_audit", ml.config.Database.Table)
+// pollChanges lit un lot de lignes non traitées au-delà de la dernière id lue
+// et renvoie le nombre de lignes mises en file. La position est remise à zéro
+// dès qu'un lot n'est pas plein, pour relire les lignes dont l'id a été
+// attribuée avant celles déjà lues mais qui ont été validées après.
+func (ml *MySQLListener) pollChanges(ctx context.Context) (int, error) {
+	auditTable := ml.auditTable()
+	batchSize := ml.config.Listener.BatchSize
 
 	// Récupérer les changements non traités
 	query := fmt.Sprintf(`
 		SELECT id, operation, table_name, changed_at, data, old_data
 		FROM %s
-		WHERE processed = FALSE
-		ORDER BY changed_at ASC
-		LIMIT 100
-	`, auditTable)
+		WHERE processed = FALSE AND id > ?
+		ORDER BY id ASC
+		LIMIT %d
+	`, auditTable, batchSize)
+
+	// En mode shared, les lignes sont réservées jusqu'au commit: les autres
+	// instances les sautent et lisent les suivantes.
+	var q sqlQuerier = ml.db
+	var tx *sql.Tx
+	if ml.config.HA.Mode == "shared" {
+		var err error
+		if tx, err = ml.db.BeginTx(ctx, nil); err != nil {
+			return 0, i18n.Errorf("erreur transaction audit: %w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+		query += " FOR UPDATE SKIP LOCKED"
+	}
 
-	rows, err := ml.db.QueryContext(ctx, query)
+	rows, err := q.QueryContext(ctx, query, ml.lastID)
 	if err != nil {
-		return fmt.Errorf("erreur query audit: %w", err)
+		return 0, i18n.Errorf("erreur query audit: %w", err)
 	}
 	defer rows.Close()
 
 	var ids []int64
+	read := 0
 	for rows.Next() {
+		read++
+
 		var (
 			id        int64
 			operation string
@@ -225,14 +211,18 @@ func (ml *MySQLListener) pollChanges(ctx context.Context) error {
 			oldData   sql.NullString
 		)
 
-		if err := rows.Scan(&id, &operation, &tableName, &changedAt, &data, &oldData); err != nil {
-			ml.logger.Error("Erreur scan row: %v", err)
-			continue
-		}
-
+		// Une ligne illisible est marquée comme traitée pour ne pas bloquer le
+		// polling; elle reste dans la table d'audit. Scan renseigne les
+		// colonnes dans l'ordre: id est connue si l'erreur porte sur une autre.
+		err := rows.Scan(&id, &operation, &tableName, &changedAt, &data, &oldData)
 		var dataMap map[string]interface{}
-		if err := json.Unmarshal(data, &dataMap); err != nil {
-			ml.logger.Error("Erreur unmarshal data: %v", err)
+		if err == nil {
+			dataMap, err = decodeRow(data)
+		}
+		if err != nil {
+			ml.logger.Error("Ligne d'audit %d illisible, marquée comme traitée: %v", id, err)
+			ids = append(ids, id)
+			ml.lastID = id
 			continue
 		}
 
@@ -244,32 +234,55 @@ func (ml *MySQLListener) pollChanges(ctx context.Context) error {
 		}
 
 		if oldData.Valid {
-			var oldDataMap map[string]interface{}
-			if err := json.Unmarshal([]byte(oldData.String), &oldDataMap); err == nil {
+			if oldDataMap, err := decodeRow([]byte(oldData.String)); err == nil {
 				event.OldData = oldDataMap
 			}
 		}
-
-		select {
-		case ml.eventCh <- event:
-			ids = append(ids, id)
-		default:
-			ml.logger.Warn("Canal d'événements plein")
+		ml.types.Load().apply(event)
+		stamp(event, eventSource(ml.config, ml.table), strconv.FormatInt(id, 10))
+
+		// Un événement refusé n'est pas marqué: le lot s'arrête là pour
+		// préserver l'ordre, il sera relu au prochain polling.
+		if err := ml.gate.push(ctx, event); err != nil {
+			if ctx.Err() == nil {
+				ml.logger.With(logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID).
+					Warn("Événement %d non mis en file (%s): %v", id, ml.queue.Policy(), err)
+			}
+			read = 0
+			break
 		}
+		ids = append(ids, id)
+		ml.lastID = id
 	}
 
-	// Marquer comme traité
+	rows.Close()
+
+	// Marquer comme traité. En mode shared, un échec annule la transaction:
+	// valider libérerait les verrous de lignes encore non traitées, qu'une
+	// autre instance enverrait une seconde fois.
 	if len(ids) > 0 {
-		if err := ml.markProcessed(ids); err != nil {
+		if err := ml.markProcessed(ctx, q, ids); err != nil {
+			if tx != nil {
+				return len(ids), i18n.Errorf("erreur marquage processed: %w", err)
+			}
 			ml.logger.Error("Erreur marquage processed: %v", err)
 		}
 	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return len(ids), i18n.Errorf("erreur commit audit: %w", err)
+		}
+	}
 
-	return nil
+	if read < batchSize {
+		ml.lastID = 0
+	}
+
+	return len(ids), nil
 }
 
-func (ml *MySQLListener) markProcessed(ids []int64) error {
-	auditTable := fmt.Sprintf("%s_audit", ml.config.Database.Table)
+func (ml *MySQLListener) markProcessed(ctx context.Context, q sqlQuerier, ids []int64) error {
+	auditTable := ml.auditTable()
 
 	query := fmt.Sprintf("UPDATE %s SET processed = TRUE WHERE id IN (?", auditTable)
 	args := make([]interface{}, len(ids))
@@ -281,27 +294,22 @@ func (ml *MySQLListener) markProcessed(ids []int64) error {
 	}
 	query += ")"
 
-	_, err := ml.db.Exec(query, args...)
+	_, err := q.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (ml *MySQLListener) worker(ctx context.Context, id int) {
-	ml.logger.Debug("Worker %d démarré", id)
-
-	for {
-		select {
-		case <-ctx.Done():
-			ml.logger.Debug("Worker %d arrêté", id)
-			return
-		case event := <-ml.eventCh:
-			if err := ml.notifier.Notify(event); err != nil {
-				ml.logger.Error("Worker %d: Erreur notification: %v", id, err)
-			}
-		}
-	}
+// SetPoolSize ajuste le nombre de workers sans interrompre l'écoute.
+func (ml *MySQLListener) SetPoolSize(size int) {
+	ml.workers.resize(size)
 }
 
 func (ml *MySQLListener) Close() error {
+	if ml.queue != nil {
+		ml.queue.Close()
+	}
+	if ml.maintenance != nil {
+		ml.maintenance.Close()
+	}
 	if ml.db != nil {
 		return ml.db.Close()
 	}
@@ -0,0 +1,509 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/sqlident"
+)
+
+// registryTable liste les objets créés par paypayo. MySQL ne permettant pas de
+// commenter un trigger, le marqueur de propriété est stocké ici.
+const registryTable = "paypayo_objects"
+
+const errNoSuchTable = 1146
+
+// pollIndex permet au polling de parcourir les lignes non traitées dans
+// l'ordre des id.
+const pollIndex = "idx_processed_id"
+
+// mysqlObjects génère et gère la table d'audit et les triggers d'une table
+// MySQL.
+type mysqlObjects struct {
+	db     *sql.DB
+	table  sqlident.Name
+	server mysqlServer
+	config *config.Config
+	logger *logger.Logger
+}
+
+// mysqlDSN force le fuseau de session à UTC: les DATETIME et TIMESTAMP lus
+// sans décalage sont interprétés en UTC (parseTime, snapshots).
+func mysqlDSN(cfg *config.Config) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.Database,
+	)
+}
+
+func openMySQLObjects(cfg *config.Config, log *logger.Logger) (*mysqlObjects, error) {
+	table, err := sqlident.ParseName(cfg.Database.Table)
+	if err == nil {
+		err = table.Validate(my)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("nom de table invalide: %w", err)
+	}
+	if table.Schema == "" {
+		table.Schema = cfg.Database.Schema
+	}
+
+	db, err := sql.Open("mysql", mysqlDSN(cfg))
+	if err != nil {
+		return nil, i18n.Errorf("erreur connexion MySQL: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, i18n.Errorf("erreur ping MySQL: %w", err)
+	}
+
+	server, err := detectMySQLServer(context.Background(), db)
+	if err == nil {
+		err = server.check(cfg)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Debug("Serveur détecté: %s (%s)", server, server.version)
+
+	return &mysqlObjects{db: db, table: table, server: server, config: cfg, logger: log.With(logger.FieldTable, table.String())}, nil
+}
+
+// derived renvoie le nom d'un objet dérivé de la table, dans le même schéma,
+// raccourci comme sur PostgreSQL pour tenir dans les 64 caractères de MySQL.
+func (o *mysqlObjects) derived(suffix string) sqlident.Name {
+	return sqlident.Name{Schema: o.table.Schema, Name: my.Shorten(o.table.Name + suffix)}
+}
+
+// auditTable est créée dans le même schéma que la table surveillée.
+func (o *mysqlObjects) auditTable() string {
+	return my.QuoteName(o.derived("_audit"))
+}
+
+func (o *mysqlObjects) archiveTable() string {
+	return my.QuoteName(o.derived("_audit_archive"))
+}
+
+func (o *mysqlObjects) triggerName(op string) sqlident.Name {
+	return o.derived("_" + op + "_trigger")
+}
+
+func (o *mysqlObjects) registry() string {
+	return my.QuoteName(sqlident.Name{Schema: o.table.Schema, Name: registryTable})
+}
+
+func (o *mysqlObjects) plan(ctx context.Context) ([]dbObject, error) {
+	columns, err := o.columns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.objects(columns), nil
+}
+
+// objects génère le DDL des objets paypayo pour les colonnes données.
+func (o *mysqlObjects) objects(columns []column) []dbObject {
+	auditTable := o.auditTable()
+	table := my.QuoteName(o.table)
+
+	objects := []dbObject{
+		{
+			kind: "TABLE",
+			name: o.derived("_audit").String(),
+			create: []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			operation VARCHAR(10) NOT NULL,
+			table_name VARCHAR(255) NOT NULL,
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			data %s,
+			old_data %s,
+			processed BOOLEAN DEFAULT FALSE,
+			INDEX idx_processed (processed, changed_at)
+		)`, auditTable, o.server.jsonColumnType(), o.server.jsonColumnType())},
+			drop:     "DROP TABLE " + auditTable,
+			enabled:  true,
+			preserve: true,
+		},
+		{
+			kind:    "INDEX",
+			name:    o.derived("_audit").String() + "." + pollIndex,
+			create:  []string{fmt.Sprintf("CREATE INDEX %s ON %s (processed, id)", my.Quote(pollIndex), auditTable)},
+			drop:    fmt.Sprintf("DROP INDEX %s ON %s", my.Quote(pollIndex), auditTable),
+			enabled: true,
+		},
+	}
+
+	// La table d'archive n'est gérée que si l'archivage est activé: la
+	// désactiver ne supprime pas les lignes déjà archivées.
+	if o.config.Audit.Archive {
+		archive := o.archiveTable()
+		objects = append(objects, dbObject{
+			kind:     "TABLE",
+			name:     o.derived("_audit_archive").String(),
+			create:   []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", archive, auditTable)},
+			drop:     "DROP TABLE " + archive,
+			enabled:  true,
+			preserve: true,
+		})
+	}
+
+	newColumns := o.jsonObjectArgs(columns, "NEW")
+	oldColumns := o.jsonObjectArgs(columns, "OLD")
+
+	triggers := []struct {
+		op      string
+		enabled bool
+		columns string
+		values  string
+	}{
+		{"insert", o.config.Listener.IsInsertEnabled(), "data", fmt.Sprintf("JSON_OBJECT(%s)", newColumns)},
+		{"update", o.config.Listener.IsUpdateEnabled(), "data, old_data", fmt.Sprintf("JSON_OBJECT(%s), JSON_OBJECT(%s)", newColumns, oldColumns)},
+		{"delete", o.config.Listener.IsDeleteEnabled(), "data", fmt.Sprintf("JSON_OBJECT(%s)", oldColumns)},
+	}
+
+	for _, t := range triggers {
+		op := strings.ToUpper(t.op)
+		name := my.QuoteName(o.triggerName(t.op))
+
+		objects = append(objects, dbObject{
+			kind: "TRIGGER",
+			name: o.triggerName(t.op).String(),
+			create: []string{fmt.Sprintf(`
+			CREATE TRIGGER %s
+			AFTER %s ON %s
+			FOR EACH ROW
+			INSERT INTO %s (operation, table_name, %s)
+			VALUES ('%s', %s, %s)`, name, op, table, auditTable, t.columns, op, my.Literal(o.table.String()), t.values)},
+			drop:    "DROP TRIGGER " + name,
+			enabled: t.enabled,
+		})
+	}
+
+	return objects
+}
+
+// column décrit une colonne de la table surveillée.
+type column struct {
+	name       string
+	columnType string // type complet, ex: decimal(12,2)
+	dataType   string // type de base, ex: decimal
+}
+
+// columns lit les colonnes de la table dans l'ordre de leur définition.
+func (o *mysqlObjects) columns(ctx context.Context) ([]column, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, DATA_TYPE
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`, o.table.Schema, o.table.Name)
+	if err != nil {
+		return nil, i18n.Errorf("erreur récupération colonnes: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.columnType, &c.dataType); err != nil {
+			return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, i18n.Errorf("aucune colonne trouvée pour la table %s", o.table)
+	}
+
+	if o.server.mariadb() {
+		if err := o.markJSONColumns(ctx, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return columns, nil
+}
+
+// markJSONColumns repère les colonnes JSON de MariaDB, déclarées LONGTEXT
+// avec une contrainte CHECK (json_valid(`colonne`)) du même nom.
+func (o *mysqlObjects) markJSONColumns(ctx context.Context, columns []column) error {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT CONSTRAINT_NAME, CHECK_CLAUSE
+		FROM information_schema.CHECK_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+	`, o.table.Schema, o.table.Name)
+	if err != nil {
+		return i18n.Errorf("erreur récupération colonnes JSON: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, clause string
+		if err := rows.Scan(&name, &clause); err != nil {
+			return i18n.Errorf("erreur lecture colonnes JSON: %w", err)
+		}
+		for i := range columns {
+			if columns[i].name == name && clause == "json_valid("+my.Quote(name)+")" {
+				columns[i].dataType = "json"
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// buildColumnList renvoie les paires 'colonne', prefix.`colonne` attendues par
+// JSON_OBJECT pour toutes les colonnes de la table.
+func (o *mysqlObjects) buildColumnList(ctx context.Context, prefix string) (string, error) {
+	columns, err := o.columns(ctx)
+	if err != nil {
+		return "", err
+	}
+	return o.jsonObjectArgs(columns, prefix), nil
+}
+
+func (o *mysqlObjects) jsonObjectArgs(columns []column, prefix string) string {
+	pairs := make([]string, len(columns))
+	for i, c := range columns {
+		pairs[i] = fmt.Sprintf("%s, %s", my.Literal(c.name), o.server.jsonValue(c, prefix))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (o *mysqlObjects) state(ctx context.Context, obj dbObject) (objectState, error) {
+	var query string
+	var args []interface{}
+
+	switch obj.kind {
+	case "INDEX":
+		audit := o.derived("_audit")
+		query = `SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND INDEX_NAME = ?`
+		args = []interface{}{audit.Schema, audit.Name, pollIndex}
+	default:
+		name, err := sqlident.ParseName(obj.name)
+		if err != nil {
+			name = sqlident.Name{Schema: o.table.Schema, Name: obj.name}
+		}
+		query = `SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?`
+		if obj.kind == "TRIGGER" {
+			query = `SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TRIGGER_NAME = ?`
+		}
+		args = []interface{}{name.Schema, name.Name}
+	}
+
+	var count int
+	if err := o.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return stateMissing, nil
+	}
+
+	var marker string
+	err := o.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT marker FROM %s WHERE object_type = ? AND object_name = ?", o.registry()),
+		obj.kind, obj.name).Scan(&marker)
+
+	var myErr *mysql.MySQLError
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.As(err, &myErr) && myErr.Number == errNoSuchTable:
+		return stateForeign, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return markerState(true, marker, obj.marker()), nil
+}
+
+func (o *mysqlObjects) registryDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			object_type VARCHAR(16) NOT NULL,
+			object_name VARCHAR(255) NOT NULL,
+			marker VARCHAR(64) NOT NULL,
+			installed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (object_type, object_name)
+		)`, o.registry())
+}
+
+func (o *mysqlObjects) script(obj dbObject) []string {
+	register := fmt.Sprintf("REPLACE INTO %s (object_type, object_name, marker) VALUES (%s, %s, %s)",
+		o.registry(), my.Literal(obj.kind), my.Literal(obj.name), my.Literal(obj.marker()))
+	return append(append([]string{}, obj.create...), register)
+}
+
+// apply crée l'objet puis l'enregistre. Le DDL MySQL n'étant pas
+// transactionnel, un trigger à remplacer est supprimé puis recréé.
+func (o *mysqlObjects) apply(ctx context.Context, obj dbObject, replace bool) error {
+	if _, err := o.db.ExecContext(ctx, o.registryDDL()); err != nil {
+		return i18n.Errorf("erreur création registre %s: %w", registryTable, err)
+	}
+
+	if replace && !obj.preserve {
+		if _, err := o.db.ExecContext(ctx, obj.drop); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range o.script(obj) {
+		if _, err := o.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshTriggers recrée les triggers paypayo dont le DDL ne correspond plus
+// aux colonnes de la table. Les tables sont verrouillées pendant l'opération
+// pour qu'aucune écriture ne passe entre la suppression et la recréation.
+func (o *mysqlObjects) refreshTriggers(ctx context.Context) error {
+	objects, err := o.plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stale []dbObject
+	var replace []bool
+	for _, obj := range objects {
+		if obj.kind != "TRIGGER" || !obj.enabled {
+			continue
+		}
+		st, err := o.state(ctx, obj)
+		if err != nil {
+			return i18n.Errorf("erreur lecture état %s: %w", obj, err)
+		}
+		switch st {
+		case stateForeign:
+			return i18n.Errorf("%s n'a pas été créé par paypayo", obj)
+		case stateMissing, stateOutdated:
+			stale = append(stale, obj)
+			replace = append(replace, st == stateOutdated)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// Le registre peut manquer (triggers installés à la main ou par script):
+	// LOCK TABLES échouerait sur une table absente.
+	if _, err := o.db.ExecContext(ctx, o.registryDDL()); err != nil {
+		return i18n.Errorf("erreur création registre %s: %w", registryTable, err)
+	}
+
+	conn, err := o.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lock := fmt.Sprintf("LOCK TABLES %s WRITE, %s WRITE, %s WRITE", my.QuoteName(o.table), o.auditTable(), o.registry())
+	if _, err := conn.ExecContext(ctx, lock); err != nil {
+		return i18n.Errorf("erreur verrouillage de %s: %w", o.table, err)
+	}
+	defer conn.ExecContext(context.Background(), "UNLOCK TABLES")
+
+	for i, obj := range stale {
+		if replace[i] {
+			if _, err := conn.ExecContext(ctx, obj.drop); err != nil {
+				return i18n.Errorf("erreur suppression %s: %w", obj, err)
+			}
+		}
+		for _, stmt := range o.script(obj) {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return i18n.Errorf("erreur création %s: %w", obj, err)
+			}
+		}
+		o.logger.Info("%s régénéré", obj)
+	}
+
+	return nil
+}
+
+func (o *mysqlObjects) remove(ctx context.Context, obj dbObject) error {
+	if _, err := o.db.ExecContext(ctx, obj.drop); err != nil {
+		return err
+	}
+
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE object_type = ? AND object_name = ?", o.registry()),
+		obj.kind, obj.name)
+	return err
+}
+
+func (o *mysqlObjects) Install(ctx context.Context, adopt bool) error {
+	return installObjects(ctx, o, o.logger, adopt)
+}
+
+// sharedInstall installe les objets sous GET_LOCK pour que plusieurs instances
+// démarrant ensemble (ha.mode shared) ne modifient pas les triggers en même
+// temps.
+func (o *mysqlObjects) sharedInstall(ctx context.Context, name string) error {
+	conn, err := o.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	name = my.Shorten(name)
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", name).Scan(&got); err != nil {
+		return err
+	}
+	if got.Int64 != 1 {
+		return i18n.Errorf("verrou d'installation %s non obtenu après 60 secondes", name)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+
+	return o.Install(ctx, false)
+}
+
+// Uninstall supprime aussi le registre une fois vide.
+func (o *mysqlObjects) Uninstall(ctx context.Context) error {
+	if err := uninstallObjects(ctx, o, o.logger); err != nil {
+		return err
+	}
+
+	var remaining int
+	err := o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+o.registry()).Scan(&remaining)
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errNoSuchTable {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		if _, err := o.db.ExecContext(ctx, "DROP TABLE "+o.registry()); err != nil {
+			return err
+		}
+		o.logger.Info("Registre %s supprimé", registryTable)
+	}
+	return nil
+}
+
+func (o *mysqlObjects) Verify(ctx context.Context) error {
+	return verifyObjects(ctx, o, o.logger)
+}
+
+func (o *mysqlObjects) GenerateSQL(ctx context.Context, w io.Writer) error {
+	header := i18n.Sprintf("Objets paypayo pour la table %s (modes: %s)", o.table, o.config.Listener.Modes)
+	return writeScript(ctx, w, o, header, []string{o.registryDDL()})
+}
+
+func (o *mysqlObjects) Close() error {
+	return o.db.Close()
+}
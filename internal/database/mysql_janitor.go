@@ -0,0 +1,323 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/metrics"
+)
+
+// Nombre de partitions journalières créées à l'avance.
+const partitionsAhead = 3
+
+// runJanitor supprime (ou archive) régulièrement les lignes d'audit traitées
+// plus anciennes que audit.retention_hours et met à jour les métriques de la
+// table d'audit.
+func (ml *MySQLListener) runJanitor(ctx context.Context) {
+	cfg := ml.config.Audit
+
+	ticker := time.NewTicker(time.Duration(cfg.CleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if ml.maintainer(ctx) {
+			if err := ml.cleanupAudit(ctx); err != nil && ctx.Err() == nil {
+				ml.logger.Error("Erreur nettoyage de la table d'audit: %v", err)
+			}
+		}
+		if err := ml.auditStats(ctx); err != nil && ctx.Err() == nil {
+			ml.logger.Warn("Erreur lecture statistiques d'audit: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ml *MySQLListener) cleanupAudit(ctx context.Context) error {
+	cfg := ml.config.Audit
+
+	if cfg.PartitionByDay {
+		if err := ml.partitionAudit(ctx); err != nil {
+			return i18n.Errorf("erreur partitionnement: %w", err)
+		}
+		if err := ml.addPartitions(ctx); err != nil {
+			return err
+		}
+	}
+	if cfg.RetentionHours == 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-time.Duration(cfg.RetentionHours) * time.Hour)
+	if cfg.PartitionByDay {
+		return ml.dropPartitions(ctx, cutoff)
+	}
+
+	var total int64
+	for {
+		n, err := ml.purgeBatch(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		total += n
+		if n < int64(cfg.BatchSize) {
+			break
+		}
+		// Laisser passer les écritures entre deux lots
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if total > 0 {
+		metrics.AuditPurged.Add(total)
+		ml.logger.Info("Table d'audit: %d lignes traitées antérieures au %s supprimées", total, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// purgeBatch supprime au plus batch_size lignes traitées, après les avoir
+// copiées dans la table d'archive si l'archivage est activé.
+func (ml *MySQLListener) purgeBatch(ctx context.Context, cutoff time.Time) (int64, error) {
+	auditTable := ml.auditTable()
+	limit := ml.config.Audit.BatchSize
+
+	if !ml.config.Audit.Archive {
+		res, err := ml.db.ExecContext(ctx, fmt.Sprintf(
+			"DELETE FROM %s WHERE processed = TRUE AND changed_at < ? ORDER BY id LIMIT %d", auditTable, limit), cutoff)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	tx, err := ml.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id FROM %s WHERE processed = TRUE AND changed_at < ? ORDER BY id LIMIT %d FOR UPDATE", auditTable, limit), cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s SELECT * FROM %s WHERE id IN (%s)", ml.archiveTable(), auditTable, in), ids...); err != nil {
+		return 0, i18n.Errorf("erreur archivage: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE id IN (%s)", auditTable, in), ids...); err != nil {
+		return 0, err
+	}
+
+	return int64(len(ids)), tx.Commit()
+}
+
+// auditStats met à jour la taille estimée de la table d'audit et le nombre de
+// lignes en attente de traitement.
+func (ml *MySQLListener) auditStats(ctx context.Context) error {
+	var rows, bytes sql.NullInt64
+	err := ml.db.QueryRowContext(ctx, `
+		SELECT TABLE_ROWS, DATA_LENGTH + INDEX_LENGTH
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+	`, ml.table.Schema, ml.derived("_audit").Name).Scan(&rows, &bytes)
+	if err != nil {
+		return err
+	}
+	metrics.AuditRows.Set(rows.Int64)
+	metrics.AuditBytes.Set(bytes.Int64)
+
+	var backlog int64
+	if err := ml.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s WHERE processed = FALSE", ml.auditTable())).Scan(&backlog); err != nil {
+		return err
+	}
+	metrics.AuditBacklog.Set(backlog)
+	return nil
+}
+
+// auditPartition est une partition journalière de la table d'audit; bound
+// est la borne VALUES LESS THAN (UNIX_TIMESTAMP), 0 pour MAXVALUE.
+type auditPartition struct {
+	name  string
+	bound int64
+}
+
+func (ml *MySQLListener) partitions(ctx context.Context) ([]auditPartition, error) {
+	rows, err := ml.db.QueryContext(ctx, `
+		SELECT PARTITION_NAME, PARTITION_DESCRIPTION
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION
+	`, ml.table.Schema, ml.derived("_audit").Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []auditPartition
+	for rows.Next() {
+		var p auditPartition
+		var desc string
+		if err := rows.Scan(&p.name, &desc); err != nil {
+			return nil, err
+		}
+		p.bound, _ = strconv.ParseInt(desc, 10, 64)
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+func partitionName(day time.Time) string {
+	return "p" + day.Format("20060102")
+}
+
+// utcDay renvoie le début du jour UTC de t.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionAudit convertit la table d'audit en partitions journalières
+// (RANGE sur changed_at). La clé primaire doit inclure changed_at.
+func (ml *MySQLListener) partitionAudit(ctx context.Context) error {
+	parts, err := ml.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(parts) > 0 {
+		return nil
+	}
+
+	today := utcDay(time.Now())
+	defs := []string{fmt.Sprintf("PARTITION pold VALUES LESS THAN (%d)", today.Unix())}
+	for i := 0; i < partitionsAhead; i++ {
+		day := today.AddDate(0, 0, i)
+		defs = append(defs, fmt.Sprintf("PARTITION %s VALUES LESS THAN (%d)", partitionName(day), day.AddDate(0, 0, 1).Unix()))
+	}
+	defs = append(defs, "PARTITION pmax VALUES LESS THAN MAXVALUE")
+
+	ml.logger.Warn("Conversion de la table d'audit en partitions journalières, l'opération peut être longue")
+
+	_, err = ml.db.ExecContext(ctx, fmt.Sprintf(`
+		ALTER TABLE %s
+		DROP PRIMARY KEY, ADD PRIMARY KEY (id, changed_at)
+		PARTITION BY RANGE (UNIX_TIMESTAMP(changed_at)) (%s)`, ml.auditTable(), strings.Join(defs, ", ")))
+	if err != nil {
+		return err
+	}
+
+	ml.logger.Info("Table d'audit partitionnée par jour")
+	return nil
+}
+
+// addPartitions crée les partitions des prochains jours en découpant pmax.
+func (ml *MySQLListener) addPartitions(ctx context.Context) error {
+	parts, err := ml.partitions(ctx)
+	if err != nil || len(parts) == 0 {
+		return err
+	}
+
+	existing := make(map[string]bool, len(parts))
+	for _, p := range parts {
+		existing[p.name] = true
+	}
+
+	today := utcDay(time.Now())
+	for i := 0; i < partitionsAhead; i++ {
+		day := today.AddDate(0, 0, i)
+		if existing[partitionName(day)] {
+			continue
+		}
+		_, err := ml.db.ExecContext(ctx, fmt.Sprintf(`
+			ALTER TABLE %s REORGANIZE PARTITION pmax INTO (
+				PARTITION %s VALUES LESS THAN (%d),
+				PARTITION pmax VALUES LESS THAN MAXVALUE
+			)`, ml.auditTable(), partitionName(day), day.AddDate(0, 0, 1).Unix()))
+		if err != nil {
+			return i18n.Errorf("erreur création partition %s: %w", partitionName(day), err)
+		}
+		ml.logger.Info("Partition d'audit %s créée", partitionName(day))
+	}
+	return nil
+}
+
+// dropPartitions supprime les partitions entièrement antérieures à cutoff,
+// sauf si elles contiennent encore des lignes non traitées.
+func (ml *MySQLListener) dropPartitions(ctx context.Context, cutoff time.Time) error {
+	parts, err := ml.partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	auditTable := ml.auditTable()
+	for _, p := range parts {
+		if p.bound == 0 || p.bound > cutoff.Unix() {
+			continue
+		}
+
+		var pending int64
+		if err := ml.db.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s PARTITION (%s) WHERE processed = FALSE", auditTable, p.name)).Scan(&pending); err != nil {
+			return err
+		}
+		if pending > 0 {
+			ml.logger.Warn("Partition d'audit %s conservée: %d lignes non traitées", p.name, pending)
+			continue
+		}
+
+		var count int64
+		if err := ml.db.QueryRowContext(ctx, fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s PARTITION (%s)", auditTable, p.name)).Scan(&count); err != nil {
+			return err
+		}
+
+		// INSERT IGNORE: une partition déjà archivée avant un arrêt brutal
+		// n'est pas copiée deux fois.
+		if ml.config.Audit.Archive {
+			if _, err := ml.db.ExecContext(ctx, fmt.Sprintf(
+				"INSERT IGNORE INTO %s SELECT * FROM %s PARTITION (%s)", ml.archiveTable(), auditTable, p.name)); err != nil {
+				return i18n.Errorf("erreur archivage partition %s: %w", p.name, err)
+			}
+		}
+		if _, err := ml.db.ExecContext(ctx, fmt.Sprintf(
+			"ALTER TABLE %s DROP PARTITION %s", auditTable, p.name)); err != nil {
+			return i18n.Errorf("erreur suppression partition %s: %w", p.name, err)
+		}
+
+		metrics.AuditPurged.Add(count)
+		ml.logger.Info("Partition d'audit %s supprimée (%d lignes)", p.name, count)
+	}
+	return nil
+}
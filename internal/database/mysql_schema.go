@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+)
+
+// fingerprint résume les noms et types des colonnes, dans leur ordre.
+func fingerprint(columns []column) string {
+	h := sha256.New()
+	for _, c := range columns {
+		fmt.Fprintf(h, "%s %s\n", c.name, c.columnType)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// diffColumns renvoie les colonnes ajoutées, supprimées et dont le type a
+// changé entre deux lectures.
+func diffColumns(before, after []column) (added, removed, modified []string) {
+	types := make(map[string]string, len(before))
+	for _, c := range before {
+		types[c.name] = c.columnType
+	}
+
+	seen := make(map[string]bool, len(after))
+	for _, c := range after {
+		seen[c.name] = true
+		old, ok := types[c.name]
+		switch {
+		case !ok:
+			added = append(added, c.name)
+		case old != c.columnType:
+			modified = append(modified, c.name)
+		}
+	}
+	for _, c := range before {
+		if !seen[c.name] {
+			removed = append(removed, c.name)
+		}
+	}
+	return added, removed, modified
+}
+
+// checkSchema compare les colonnes de la table à la dernière empreinte
+// connue. En cas de changement, les triggers sont régénérés (si paypayo gère
+// les objets) et un événement SCHEMA_CHANGE est envoyé.
+func (ml *MySQLListener) checkSchema(ctx context.Context) error {
+	columns, err := ml.columns(ctx)
+	if err != nil {
+		return err
+	}
+	if fingerprint(columns) == ml.fingerprint {
+		return nil
+	}
+
+	added, removed, modified := diffColumns(ml.schema, columns)
+
+	if !ml.maintainer(ctx) {
+		// Une autre instance régénère les triggers et envoie l'événement.
+		ml.logger.Info("Schéma de la table %s modifié: ajoutées %v, supprimées %v, modifiées %v (maintenance assurée par une autre instance)",
+			ml.table, added, removed, modified)
+		ml.schema = columns
+		ml.fingerprint = fingerprint(columns)
+		ml.types.Store(mysqlTypes(columns))
+		return nil
+	}
+
+	ml.logger.Warn("Schéma de la table %s modifié: ajoutées %v, supprimées %v, modifiées %v",
+		ml.table, added, removed, modified)
+
+	regenerated := false
+	if ml.config.Database.ManageTriggers {
+		// L'empreinte n'est pas mise à jour: la régénération sera retentée
+		// au prochain contrôle.
+		if err := ml.refreshTriggers(ctx); err != nil {
+			return i18n.Errorf("erreur régénération triggers: %w", err)
+		}
+		regenerated = true
+	} else {
+		ml.logger.Warn("manage_triggers désactivé: triggers non régénérés, appliquez le SQL de 'paypayo generate-sql'")
+	}
+
+	ml.schema = columns
+	ml.fingerprint = fingerprint(columns)
+	ml.types.Store(mysqlTypes(columns))
+	metrics.SchemaChanges.Add(1)
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	event := &notifier.ChangeEvent{
+		Operation: notifier.OpSchemaChange,
+		Table:     ml.table.String(),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"added":                added,
+			"removed":              removed,
+			"modified":             modified,
+			"columns":              names,
+			"triggers_regenerated": regenerated,
+		},
+	}
+	stamp(event, eventSource(ml.config, ml.table), "")
+	if err := ml.gate.push(ctx, event); err != nil && ctx.Err() == nil {
+		ml.logger.With(logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID).
+			Warn("Événement %s non mis en file (%s): %v", event.Operation, ml.queue.Policy(), err)
+	}
+	return nil
+}
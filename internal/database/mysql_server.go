@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+)
+
+// Variantes de serveur compatibles avec le protocole MySQL.
+const (
+	flavorMySQL   = "mysql"
+	flavorMariaDB = "mariadb"
+	flavorTiDB    = "tidb"
+)
+
+// mysqlServer décrit le serveur détecté à la connexion.
+type mysqlServer struct {
+	flavor  string
+	version string // VERSION() complète
+	major   int
+	minor   int
+	patch   int
+}
+
+// detectMySQLServer lit VERSION(): "8.0.36", "10.11.6-MariaDB-log" ou
+// "8.0.11-TiDB-v7.5.0".
+func detectMySQLServer(ctx context.Context, db *sql.DB) (mysqlServer, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return mysqlServer{}, i18n.Errorf("erreur lecture de la version du serveur: %w", err)
+	}
+	return parseMySQLVersion(version), nil
+}
+
+func parseMySQLVersion(version string) mysqlServer {
+	s := mysqlServer{flavor: flavorMySQL, version: version}
+	number := version
+
+	switch {
+	case strings.Contains(version, "-TiDB-"):
+		s.flavor = flavorTiDB
+		number = strings.TrimPrefix(version[strings.Index(version, "-TiDB-")+len("-TiDB-"):], "v")
+	case strings.Contains(strings.ToLower(version), "mariadb"):
+		s.flavor = flavorMariaDB
+		// Préfixe de compatibilité des anciens clients de réplication
+		number = strings.TrimPrefix(version, "5.5.5-")
+	}
+
+	parts := strings.SplitN(number, ".", 3)
+	nums := []*int{&s.major, &s.minor, &s.patch}
+	for i, p := range parts {
+		end := strings.IndexFunc(p, func(r rune) bool { return r < '0' || r > '9' })
+		if end >= 0 {
+			p = p[:end]
+		}
+		*nums[i], _ = strconv.Atoi(p)
+	}
+	return s
+}
+
+func (s mysqlServer) String() string {
+	name := map[string]string{flavorMySQL: "MySQL", flavorMariaDB: "MariaDB", flavorTiDB: "TiDB"}[s.flavor]
+	return fmt.Sprintf("%s %d.%d.%d", name, s.major, s.minor, s.patch)
+}
+
+func (s mysqlServer) atLeast(major, minor, patch int) bool {
+	if s.major != major {
+		return s.major > major
+	}
+	if s.minor != minor {
+		return s.minor > minor
+	}
+	return s.patch >= patch
+}
+
+func (s mysqlServer) mariadb() bool {
+	return s.flavor == flavorMariaDB
+}
+
+// check refuse les serveurs qui n'offrent pas ce dont dépendent la table
+// d'audit et les triggers.
+func (s mysqlServer) check(cfg *config.Config) error {
+	switch s.flavor {
+	case flavorTiDB:
+		return i18n.Errorf("%s non supporté: TiDB n'implémente pas les triggers, utilisez TiCDC pour répliquer la table vers MySQL ou MariaDB", s)
+	case flavorMariaDB:
+		// JSON_OBJECT, plusieurs triggers par table et information_schema.CHECK_CONSTRAINTS
+		if !s.atLeast(10, 3, 10) {
+			return i18n.Errorf("%s non supporté: MariaDB 10.3.10 ou plus récent requis", s)
+		}
+		if cfg.HA.Mode == "shared" && !s.atLeast(10, 6, 0) {
+			return i18n.Errorf("ha.mode shared nécessite MariaDB 10.6 (SKIP LOCKED), serveur %s", s)
+		}
+	default:
+		// Type JSON (5.7.8) et plusieurs triggers par événement (5.7.2)
+		if !s.atLeast(5, 7, 8) {
+			return i18n.Errorf("%s non supporté: MySQL 5.7.8 ou plus récent requis", s)
+		}
+		if cfg.HA.Mode == "shared" && !s.atLeast(8, 0, 1) {
+			return i18n.Errorf("ha.mode shared nécessite MySQL 8.0.1 (SKIP LOCKED), serveur %s", s)
+		}
+	}
+	return nil
+}
+
+// jsonValue renvoie l'expression passée à JSON_OBJECT pour une colonne.
+// MariaDB stocke le JSON en LONGTEXT, qu'il faut extraire pour ne pas
+// l'imbriquer en chaîne, et n'encode pas les binaires: ils sont convertis au
+// format "base64:typeNN:" de MySQL.
+func (s mysqlServer) jsonValue(c column, prefix string) string {
+	value := prefix + "." + my.Quote(c.name)
+	if !s.mariadb() {
+		return value
+	}
+	switch mysqlType(c.dataType, c.columnType) {
+	case typeJSON:
+		return fmt.Sprintf("JSON_EXTRACT(%s, '$')", value)
+	case typeBinary:
+		return fmt.Sprintf("CONCAT('base64:type15:', REPLACE(TO_BASE64(%s), CHAR(10), ''))", value)
+	}
+	return value
+}
+
+// jsonColumnType est le type des colonnes data et old_data de la table
+// d'audit: JSON n'est qu'un alias de LONGTEXT avec contrôle JSON_VALID sur
+// MariaDB, inutile pour des valeurs produites par JSON_OBJECT.
+func (s mysqlServer) jsonColumnType() string {
+	if s.mariadb() {
+		return "LONGTEXT"
+	}
+	return "JSON"
+}
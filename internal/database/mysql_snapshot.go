@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/notifier"
+)
+
+// Snapshot envoie le contenu actuel de la table sous forme d'événements
+// SNAPSHOT, en reprenant au dernier checkpoint s'il existe.
+func (ml *MySQLListener) Snapshot(ctx context.Context) error {
+	return runSnapshot(ctx, &mysqlChunkReader{ml: ml}, eventSource(ml.config, ml.table), ml.types.Load(), ml.config, ml.logger, ml.gate, ml.workers)
+}
+
+// mysqlChunkReader écrit ses watermarks dans la table d'audit, déjà marqués
+// comme traités pour que le polling les ignore. Les lignes d'audit comprises
+// entre les deux watermarks forment la fenêtre de conflit.
+type mysqlChunkReader struct {
+	ml *MySQLListener
+}
+
+func (r *mysqlChunkReader) primaryKey(ctx context.Context) ([]string, error) {
+	rows, err := r.ml.db.QueryContext(ctx, `
+		SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION
+	`, r.ml.table.Schema, r.ml.table.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pk []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		pk = append(pk, col)
+	}
+	return pk, rows.Err()
+}
+
+func (r *mysqlChunkReader) readChunk(ctx context.Context, pk []string, after []interface{}, limit int) ([]map[string]interface{}, []interface{}, error) {
+	low, err := r.insertWatermark(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	window := newChunkWindow(pk)
+	var lastKey []interface{}
+
+	query, args, err := r.chunkQuery(ctx, pk, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := r.ml.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		row, err := decodeRow(raw)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		window.add(row)
+		lastKey = keyValues(row, pk)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	high, err := r.insertWatermark(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.applyWindow(ctx, low, high, window); err != nil {
+		return nil, nil, err
+	}
+
+	return window.result(), lastKey, nil
+}
+
+func (r *mysqlChunkReader) applyWindow(ctx context.Context, low, high int64, window *chunkWindow) error {
+	auditTable := r.ml.auditTable()
+
+	rows, err := r.ml.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT data, old_data FROM %s
+		WHERE id > ? AND id < ? AND operation <> ?
+	`, auditTable), low, high, notifier.OpWatermark)
+	if err != nil {
+		return i18n.Errorf("erreur lecture fenêtre de watermarks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		var oldData sql.NullString
+		if err := rows.Scan(&data, &oldData); err != nil {
+			return err
+		}
+
+		var event notifier.ChangeEvent
+		event.Data, _ = decodeRow(data)
+		if oldData.Valid {
+			event.OldData, _ = decodeRow([]byte(oldData.String))
+		}
+		window.conflict(&event)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = r.ml.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id IN (?, ?)", auditTable), low, high)
+	return err
+}
+
+func (r *mysqlChunkReader) insertWatermark(ctx context.Context) (int64, error) {
+	auditTable := r.ml.auditTable()
+
+	res, err := r.ml.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (operation, table_name, data, processed)
+		VALUES (?, ?, JSON_OBJECT('watermark', ?), TRUE)
+	`, auditTable), notifier.OpWatermark, r.ml.table.String(), newWatermark())
+	if err != nil {
+		return 0, i18n.Errorf("erreur écriture watermark: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *mysqlChunkReader) chunkQuery(ctx context.Context, pk []string, after []interface{}, limit int) (string, []interface{}, error) {
+	columns, err := r.ml.buildColumnList(ctx, "t")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cols := my.QuoteList(pk)
+	selectSQL := fmt.Sprintf("SELECT JSON_OBJECT(%s) FROM %s t", columns, my.QuoteName(r.ml.table))
+
+	if after == nil {
+		return fmt.Sprintf("%s ORDER BY %s LIMIT %d", selectSQL, cols, limit), nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(pk)), ", ")
+	return fmt.Sprintf("%s WHERE (%s) > (%s) ORDER BY %s LIMIT %d", selectSQL, cols, placeholders, cols, limit), after, nil
+}
+
+func (r *mysqlChunkReader) close() error {
+	return nil
+}
@@ -2,180 +2,120 @@ package database
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/lib/pq"
 
 	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
 	"app-db-listener/internal/logger"
 	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+	"app-db-listener/internal/sqlident"
 )
 
+const pg = sqlident.Postgres
+
 type PostgresListener struct {
-	db       *sql.DB
+	*pgObjects
+	connStr  string
 	listener *pq.Listener
-	config   *config.Config
-	logger   *logger.Logger
 	notifier *notifier.Notifier
-	eventCh  chan *notifier.ChangeEvent
+	queue    *queue.Queue
+	gate     *snapshotGate
+	workers  *workerPool
+	types    *tableTypes
 }
 
 func NewPostgresListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (*PostgresListener, error) {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Database,
-		cfg.Database.SSLMode,
-	)
-
-	db, err := sql.Open("postgres", connStr)
+	objects, err := openPostgresObjects(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.ManageTriggers {
+		err = objects.Install(context.Background(), false)
+	} else {
+		err = objects.Verify(context.Background())
+	}
 	if err != nil {
-		return nil, fmt.Errorf("erreur connexion PostgreSQL: %w", err)
+		objects.Close()
+		return nil, i18n.Errorf("erreur setup triggers: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("erreur ping PostgreSQL: %w", err)
+	types, err := objects.columnTypes(context.Background())
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur lecture types des colonnes: %w", err)
 	}
 
+	connStr := postgresConnString(cfg)
+
 	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
 		if err != nil {
 			log.Error("Événement listener: %v", err)
 		}
 	})
 
-	pl := &PostgresListener{
-		db:       db,
-		listener: listener,
-		config:   cfg,
-		logger:   log,
-		notifier: ntf,
-		eventCh:  make(chan *notifier.ChangeEvent, 100),
-	}
-
-	if err := pl.setupTriggers(); err != nil {
-		return nil, fmt.Errorf("erreur setup triggers: %w", err)
+	q, err := queue.New(&cfg.Worker, log)
+	if err != nil {
+		listener.Close()
+		objects.Close()
+		return nil, i18n.Errorf("erreur création file d'événements: %w", err)
 	}
 
-	return pl, nil
+	return &PostgresListener{
+		pgObjects: objects,
+		connStr:   connStr,
+		listener:  listener,
+		notifier:  ntf,
+		queue:     q,
+		gate:      newSnapshotGate(q),
+		workers:   newWorkerPool(q, ntf, log),
+		types:     types,
+	}, nil
 }
 
-func (pl *PostgresListener) setupTriggers() error {
-	channelName := fmt.Sprintf("%s_changes", pl.config.Database.Table)
-
-	functionSQL := fmt.Sprintf(`
-		CREATE OR REPLACE FUNCTION notify_%s_changes()
-		RETURNS TRIGGER AS $$
-		DECLARE
-			payload JSON;
-		BEGIN
-			IF (TG_OP = 'DELETE') THEN
-				payload = json_build_object(
-					'operation', TG_OP,
-					'table', TG_TABLE_NAME,
-					'timestamp', NOW(),
-					'data', row_to_json(OLD)
-				);
-			ELSIF (TG_OP = 'UPDATE') THEN
-				payload = json_build_object(
-					'operation', TG_OP,
-					'table', TG_TABLE_NAME,
-					'timestamp', NOW(),
-					'data', row_to_json(NEW),
-					'old_data', row_to_json(OLD)
-				);
-			ELSIF (TG_OP = 'INSERT') THEN
-				payload = json_build_object(
-					'operation', TG_OP,
-					'table', TG_TABLE_NAME,
-					'timestamp', NOW(),
-					'data', row_to_json(NEW)
-				);
-			END IF;
-			
-			PERFORM pg_notify('%s', payload::text);
-			
-			RETURN NULL;
-		END;
-		$$ LANGUAGE plpgsql;
-	`, pl.config.Database.Table, channelName)
-
-	if _, err := pl.db.Exec(functionSQL); err != nil {
-		return fmt.Errorf("erreur création fonction: %w", err)
-	}
-
-	dropTriggerSQL := fmt.Sprintf(`
-		DROP TRIGGER IF EXISTS %s_insert_trigger ON %s;
-		DROP TRIGGER IF EXISTS %s_update_trigger ON %s;
-		DROP TRIGGER IF EXISTS %s_delete_trigger ON %s;
-	`,
-		pl.config.Database.Table, pl.config.Database.Table,
-		pl.config.Database.Table, pl.config.Database.Table,
-		pl.config.Database.Table, pl.config.Database.Table,
-	)
-
-	if _, err := pl.db.Exec(dropTriggerSQL); err != nil {
-		return fmt.Errorf("erreur suppression triggers: %w", err)
+// columnTypes lit le type de chaque colonne de la table.
+func (o *pgObjects) columnTypes(ctx context.Context) (*tableTypes, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT a.attname, t.typname, format_type(a.atttypid, a.atttypmod)
+		FROM pg_attribute a JOIN pg_type t ON t.oid = a.atttypid
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, pg.QuoteName(o.table))
+	if err != nil {
+		return nil, err
 	}
-
-	if pl.config.Listener.IsInsertEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_insert_trigger
-			AFTER INSERT ON %s
-			FOR EACH ROW EXECUTE FUNCTION notify_%s_changes();
-		`, pl.config.Database.Table, pl.config.Database.Table, pl.config.Database.Table)
-
-		if _, err := pl.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger INSERT: %w", err)
+	defer rows.Close()
+
+	var fields []notifier.Field
+	for rows.Next() {
+		var f notifier.Field
+		var typname string
+		if err := rows.Scan(&f.Name, &typname, &f.DBType); err != nil {
+			return nil, err
 		}
-		pl.logger.Info("Trigger INSERT créé pour la table %s", pl.config.Database.Table)
+		f.Type, f.Items = pgType(typname)
+		fields = append(fields, f)
 	}
-
-	if pl.config.Listener.IsUpdateEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_update_trigger
-			AFTER UPDATE ON %s
-			FOR EACH ROW EXECUTE FUNCTION notify_%s_changes();
-		`, pl.config.Database.Table, pl.config.Database.Table, pl.config.Database.Table)
-
-		if _, err := pl.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger UPDATE: %w", err)
-		}
-		pl.logger.Info("Trigger UPDATE créé pour la table %s", pl.config.Database.Table)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	if pl.config.Listener.IsDeleteEnabled() {
-		triggerSQL := fmt.Sprintf(`
-			CREATE TRIGGER %s_delete_trigger
-			AFTER DELETE ON %s
-			FOR EACH ROW EXECUTE FUNCTION notify_%s_changes();
-		`, pl.config.Database.Table, pl.config.Database.Table, pl.config.Database.Table)
-
-		if _, err := pl.db.Exec(triggerSQL); err != nil {
-			return fmt.Errorf("erreur création trigger DELETE: %w", err)
-		}
-		pl.logger.Info("Trigger DELETE créé pour la table %s", pl.config.Database.Table)
-	}
-
-	return nil
+	return newTableTypes(fields), nil
 }
 
 func (pl *PostgresListener) Listen(ctx context.Context) error {
-	channelName := fmt.Sprintf("%s_changes", pl.config.Database.Table)
+	channelName := pl.channelName()
 
 	if err := pl.listener.Listen(channelName); err != nil {
-		return fmt.Errorf("erreur LISTEN: %w", err)
+		return i18n.Errorf("erreur LISTEN: %w", err)
 	}
 
 	pl.logger.Info("Écoute démarrée sur le canal: %s", channelName)
 
-	for i := 0; i < pl.config.Worker.PoolSize; i++ {
-		go pl.worker(ctx, i)
-	}
+	pl.workers.start(ctx, pl.config.Worker.PoolSize)
 
 	for {
 		select {
@@ -187,16 +127,27 @@ func (pl *PostgresListener) Listen(ctx context.Context) error {
 				continue
 			}
 
-			var event notifier.ChangeEvent
-			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+			event, err := decodeEvent([]byte(n.Extra))
+			if err != nil {
 				pl.logger.Error("Erreur unmarshalling notification: %v", err)
 				continue
 			}
 
-			select {
-			case pl.eventCh <- &event:
-			default:
-				pl.logger.Warn("Canal d'événements plein, événement perdu")
+			if event.Operation == notifier.OpWatermark {
+				continue
+			}
+			pl.types.apply(event)
+
+			// LSN lu par le trigger au moment du changement
+			var position string
+			if event.Source != nil {
+				position = event.Source.Position
+			}
+			stamp(event, eventSource(pl.config, pl.table), position)
+
+			if err := pl.gate.push(ctx, event); err != nil && ctx.Err() == nil {
+				pl.logger.With(logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID).
+					Warn("Événement %s non mis en file (%s): %v", event.Operation, pl.queue.Policy(), err)
 			}
 		case <-time.After(90 * time.Second):
 			go func() {
@@ -206,26 +157,18 @@ func (pl *PostgresListener) Listen(ctx context.Context) error {
 	}
 }
 
-func (pl *PostgresListener) worker(ctx context.Context, id int) {
-	pl.logger.Debug("Worker %d démarré", id)
-
-	for {
-		select {
-		case <-ctx.Done():
-			pl.logger.Debug("Worker %d arrêté", id)
-			return
-		case event := <-pl.eventCh:
-			if err := pl.notifier.Notify(event); err != nil {
-				pl.logger.Error("Worker %d: Erreur notification: %v", id, err)
-			}
-		}
-	}
+// SetPoolSize ajuste le nombre de workers sans interrompre l'écoute.
+func (pl *PostgresListener) SetPoolSize(size int) {
+	pl.workers.resize(size)
 }
 
 func (pl *PostgresListener) Close() error {
 	if pl.listener != nil {
 		pl.listener.Close()
 	}
+	if pl.queue != nil {
+		pl.queue.Close()
+	}
 	if pl.db != nil {
 		return pl.db.Close()
 	}
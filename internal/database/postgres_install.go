@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/sqlident"
+)
+
+// pgObjects génère et gère les objets paypayo d'une table PostgreSQL. Le
+// marqueur de propriété est posé avec COMMENT ON.
+type pgObjects struct {
+	db     *sql.DB
+	table  sqlident.Name
+	config *config.Config
+	logger *logger.Logger
+}
+
+func postgresConnString(cfg *config.Config) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Database,
+		cfg.Database.SSLMode,
+	)
+}
+
+func openPostgresObjects(cfg *config.Config, log *logger.Logger) (*pgObjects, error) {
+	table, err := sqlident.ParseName(cfg.Database.Table)
+	if err == nil {
+		err = table.Validate(pg)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("nom de table invalide: %w", err)
+	}
+	if table.Schema == "" {
+		table.Schema = cfg.Database.Schema
+	}
+	if table.Schema == "" {
+		table.Schema = "public"
+	}
+
+	db, err := sql.Open("postgres", postgresConnString(cfg))
+	if err != nil {
+		return nil, i18n.Errorf("erreur connexion PostgreSQL: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, i18n.Errorf("erreur ping PostgreSQL: %w", err)
+	}
+
+	return &pgObjects{db: db, table: table, config: cfg, logger: log.With(logger.FieldTable, table.String())}, nil
+}
+
+// channelName et functionName dérivent du schéma et de la table pour que deux
+// tables de même nom dans des schémas différents ne partagent rien.
+func (o *pgObjects) channelName() string {
+	return pg.Shorten(fmt.Sprintf("paypayo_%s_%s", o.table.Schema, o.table.Name))
+}
+
+// functionName est créée dans le schéma dédié aux objets paypayo.
+func (o *pgObjects) functionName() sqlident.Name {
+	return sqlident.Name{
+		Schema: o.config.Database.ObjectsSchema,
+		Name:   pg.Shorten(fmt.Sprintf("notify_%s_%s", o.table.Schema, o.table.Name)),
+	}
+}
+
+func (o *pgObjects) triggerName(op string) string {
+	return pg.Shorten(fmt.Sprintf("%s_%s_trigger", o.table.Name, op))
+}
+
+func (o *pgObjects) plan(ctx context.Context) ([]dbObject, error) {
+	table := pg.QuoteName(o.table)
+	function := pg.QuoteName(o.functionName())
+	objectsSchema := pg.Quote(o.config.Database.ObjectsSchema)
+
+	objects := []dbObject{
+		{
+			kind:    "SCHEMA",
+			name:    o.config.Database.ObjectsSchema,
+			create:  []string{"CREATE SCHEMA IF NOT EXISTS " + objectsSchema},
+			drop:    "DROP SCHEMA " + objectsSchema,
+			enabled: true,
+			shared:  true,
+		},
+		{
+			kind: "FUNCTION",
+			name: o.functionName().String(),
+			create: []string{fmt.Sprintf(`
+		CREATE OR REPLACE FUNCTION %s()
+		RETURNS TRIGGER AS $$
+		DECLARE
+			payload JSON;
+		BEGIN
+			IF (TG_OP = 'DELETE') THEN
+				payload = json_build_object(
+					'operation', TG_OP,
+					'table', TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME,
+					'timestamp', NOW(),
+					'source', json_build_object('position', pg_current_wal_lsn()),
+					'data', row_to_json(OLD)
+				);
+			ELSIF (TG_OP = 'UPDATE') THEN
+				payload = json_build_object(
+					'operation', TG_OP,
+					'table', TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME,
+					'timestamp', NOW(),
+					'source', json_build_object('position', pg_current_wal_lsn()),
+					'data', row_to_json(NEW),
+					'old_data', row_to_json(OLD)
+				);
+			ELSIF (TG_OP = 'INSERT') THEN
+				payload = json_build_object(
+					'operation', TG_OP,
+					'table', TG_TABLE_SCHEMA || '.' || TG_TABLE_NAME,
+					'timestamp', NOW(),
+					'source', json_build_object('position', pg_current_wal_lsn()),
+					'data', row_to_json(NEW)
+				);
+			END IF;
+
+			PERFORM pg_notify(%s, payload::text);
+
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`, function, pg.Literal(o.channelName()))},
+			drop:    fmt.Sprintf("DROP FUNCTION %s()", function),
+			enabled: true,
+		},
+	}
+
+	triggers := []struct {
+		op      string
+		enabled bool
+	}{
+		{"insert", o.config.Listener.IsInsertEnabled()},
+		{"update", o.config.Listener.IsUpdateEnabled()},
+		{"delete", o.config.Listener.IsDeleteEnabled()},
+	}
+
+	for _, t := range triggers {
+		name := pg.Quote(o.triggerName(t.op))
+		objects = append(objects, dbObject{
+			kind: "TRIGGER",
+			name: o.triggerName(t.op),
+			create: []string{fmt.Sprintf(`
+			CREATE TRIGGER %s
+			AFTER %s ON %s
+			FOR EACH ROW EXECUTE FUNCTION %s()`, name, strings.ToUpper(t.op), table, function)},
+			drop:    fmt.Sprintf("DROP TRIGGER %s ON %s", name, table),
+			enabled: t.enabled,
+		})
+	}
+
+	return objects, nil
+}
+
+// commentTarget renvoie la cible de COMMENT ON pour un objet.
+func (o *pgObjects) commentTarget(obj dbObject) string {
+	switch obj.kind {
+	case "SCHEMA":
+		return "SCHEMA " + pg.Quote(obj.name)
+	case "FUNCTION":
+		return fmt.Sprintf("FUNCTION %s()", pg.QuoteName(o.functionName()))
+	default:
+		return fmt.Sprintf("TRIGGER %s ON %s", pg.Quote(obj.name), pg.QuoteName(o.table))
+	}
+}
+
+func (o *pgObjects) state(ctx context.Context, obj dbObject) (objectState, error) {
+	var query string
+	var args []interface{}
+
+	switch obj.kind {
+	case "SCHEMA":
+		query = `SELECT obj_description(oid, 'pg_namespace') FROM pg_namespace WHERE nspname = $1`
+		args = []interface{}{obj.name}
+	case "FUNCTION":
+		fn := o.functionName()
+		query = `
+			SELECT obj_description(p.oid, 'pg_proc')
+			FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2 AND p.pronargs = 0`
+		args = []interface{}{fn.Schema, fn.Name}
+	default:
+		query = `
+			SELECT obj_description(t.oid, 'pg_trigger')
+			FROM pg_trigger t
+			WHERE t.tgrelid = $1::regclass AND t.tgname = $2`
+		args = []interface{}{pg.QuoteName(o.table), obj.name}
+	}
+
+	var comment sql.NullString
+	err := o.db.QueryRowContext(ctx, query, args...).Scan(&comment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stateMissing, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return markerState(true, comment.String, obj.marker()), nil
+}
+
+func (o *pgObjects) script(obj dbObject) []string {
+	comment := fmt.Sprintf("COMMENT ON %s IS %s", o.commentTarget(obj), pg.Literal(obj.marker()))
+	return append(append([]string{}, obj.create...), comment)
+}
+
+// apply crée l'objet et pose son marqueur dans une même transaction.
+func (o *pgObjects) apply(ctx context.Context, obj dbObject, replace bool) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if replace && !obj.preserve && obj.kind == "TRIGGER" {
+		if _, err := tx.ExecContext(ctx, obj.drop); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range o.script(obj) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (o *pgObjects) remove(ctx context.Context, obj dbObject) error {
+	_, err := o.db.ExecContext(ctx, obj.drop)
+	return err
+}
+
+func (o *pgObjects) Install(ctx context.Context, adopt bool) error {
+	return installObjects(ctx, o, o.logger, adopt)
+}
+
+func (o *pgObjects) Uninstall(ctx context.Context) error {
+	return uninstallObjects(ctx, o, o.logger)
+}
+
+func (o *pgObjects) Verify(ctx context.Context) error {
+	return verifyObjects(ctx, o, o.logger)
+}
+
+func (o *pgObjects) GenerateSQL(ctx context.Context, w io.Writer) error {
+	header := i18n.Sprintf("Objets paypayo pour la table %s (modes: %s)", o.table, o.config.Listener.Modes)
+	return writeScript(ctx, w, o, header, nil)
+}
+
+func (o *pgObjects) Close() error {
+	return o.db.Close()
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/sqlident"
+)
+
+// Snapshot envoie le contenu actuel de la table sous forme d'événements
+// SNAPSHOT, en reprenant au dernier checkpoint s'il existe.
+func (pl *PostgresListener) Snapshot(ctx context.Context) error {
+	watch := pq.NewListener(pl.connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			pl.logger.Error("Événement listener snapshot: %v", err)
+		}
+	})
+	if err := watch.Listen(pl.channelName()); err != nil {
+		watch.Close()
+		return i18n.Errorf("erreur LISTEN snapshot: %w", err)
+	}
+
+	reader := &pgChunkReader{pl: pl, watch: watch}
+	return runSnapshot(ctx, reader, eventSource(pl.config, pl.table), pl.types, pl.config, pl.logger, pl.gate, pl.workers)
+}
+
+type pgChunkReader struct {
+	pl    *PostgresListener
+	watch *pq.Listener
+}
+
+func (r *pgChunkReader) primaryKey(ctx context.Context) ([]string, error) {
+	rows, err := r.pl.db.QueryContext(ctx, `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary
+		ORDER BY array_position(i.indkey::int2[], a.attnum)
+	`, pg.QuoteName(r.pl.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pk []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		pk = append(pk, col)
+	}
+	return pk, rows.Err()
+}
+
+func (r *pgChunkReader) readChunk(ctx context.Context, pk []string, after []interface{}, limit int) ([]map[string]interface{}, []interface{}, error) {
+	r.drain()
+
+	low := newWatermark()
+	if err := r.emitWatermark(ctx, low); err != nil {
+		return nil, nil, err
+	}
+
+	window := newChunkWindow(pk)
+	var lastKey []interface{}
+
+	query, args := pgChunkQuery(r.pl.table, pk, after, limit)
+	rows, err := r.pl.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		row, err := decodeRow([]byte(raw))
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		window.add(row)
+		lastKey = keyValues(row, pk)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	high := newWatermark()
+	if err := r.emitWatermark(ctx, high); err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.awaitWindow(ctx, low, high, window); err != nil {
+		return nil, nil, err
+	}
+
+	return window.result(), lastKey, nil
+}
+
+// awaitWindow lit le flux jusqu'au watermark haut et retire de la tranche les
+// lignes modifiées après le watermark bas.
+func (r *pgChunkReader) awaitWindow(ctx context.Context, low, high string, window *chunkWindow) error {
+	timeout := time.After(watermarkTimeout)
+	open := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return i18n.Errorf("watermark %s non reçu après %s", high, watermarkTimeout)
+		case n := <-r.watch.Notify:
+			if n == nil {
+				continue
+			}
+
+			event, err := decodeEvent([]byte(n.Extra))
+			if err != nil {
+				continue
+			}
+
+			if event.Operation == notifier.OpWatermark {
+				switch event.Data["watermark"] {
+				case low:
+					open = true
+				case high:
+					return nil
+				}
+				continue
+			}
+
+			if open {
+				window.conflict(event)
+			}
+		}
+	}
+}
+
+func (r *pgChunkReader) emitWatermark(ctx context.Context, mark string) error {
+	payload, err := json.Marshal(notifier.ChangeEvent{
+		Operation: notifier.OpWatermark,
+		Table:     r.pl.table.String(),
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"watermark": mark},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.pl.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", r.pl.channelName(), string(payload)); err != nil {
+		return i18n.Errorf("erreur émission watermark: %w", err)
+	}
+	return nil
+}
+
+// drain vide les notifications reçues entre deux tranches.
+func (r *pgChunkReader) drain() {
+	for {
+		select {
+		case <-r.watch.Notify:
+		default:
+			return
+		}
+	}
+}
+
+func (r *pgChunkReader) close() error {
+	return r.watch.Close()
+}
+
+func pgChunkQuery(table sqlident.Name, pk []string, after []interface{}, limit int) (string, []interface{}) {
+	cols := pg.QuoteList(pk)
+	selectSQL := fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t", pg.QuoteName(table))
+
+	if after == nil {
+		return fmt.Sprintf("%s ORDER BY %s LIMIT %d", selectSQL, cols, limit), nil
+	}
+
+	placeholders := make([]string, len(pk))
+	for i := range pk {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf("%s WHERE (%s) > (%s) ORDER BY %s LIMIT %d",
+		selectSQL, cols, strings.Join(placeholders, ", "), cols, limit)
+	return query, after
+}
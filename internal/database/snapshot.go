@@ -0,0 +1,341 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+	"app-db-listener/internal/sqlident"
+)
+
+const (
+	defaultSnapshotChunkSize  = 1000
+	defaultSnapshotCheckpoint = "paypayo-snapshot.json"
+	watermarkTimeout          = 30 * time.Second
+)
+
+// chunkReader lit la table par tranches ordonnées sur la clé primaire.
+//
+// Chaque tranche est encadrée par deux watermarks écrits dans le flux de
+// changements (approche DBLog/Debezium): les lignes de la tranche modifiées
+// entre le watermark bas et le watermark haut sont retirées du résultat, car
+// l'événement du flux est plus récent que la valeur lue.
+type chunkReader interface {
+	primaryKey(ctx context.Context) ([]string, error)
+	readChunk(ctx context.Context, pk []string, after []interface{}, limit int) (rows []map[string]interface{}, lastKey []interface{}, err error)
+	close() error
+}
+
+type snapshotCheckpoint struct {
+	Table     string        `json:"table"`
+	LastKey   []interface{} `json:"last_key"`
+	Rows      int64         `json:"rows"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// runSnapshot met les lignes en file comme les événements du flux, puis
+// attend leur envoi avant d'enregistrer le checkpoint de chaque tranche.
+func runSnapshot(ctx context.Context, reader chunkReader, source notifier.Source, types *tableTypes, cfg *config.Config, log *logger.Logger, gate *snapshotGate, workers *workerPool) error {
+	defer reader.close()
+
+	// Commande snapshot: les workers ne sont pas démarrés par Listen.
+	workers.start(ctx, cfg.Worker.PoolSize)
+
+	table := sqlident.Name{Schema: source.Schema, Name: source.Table}.String()
+
+	chunkSize := cfg.Listener.SnapshotChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultSnapshotChunkSize
+	}
+
+	path := cfg.Listener.SnapshotCheckpoint
+	if path == "" {
+		path = defaultSnapshotCheckpoint
+	}
+
+	pk, err := reader.primaryKey(ctx)
+	if err != nil {
+		return i18n.Errorf("erreur lecture clé primaire: %w", err)
+	}
+	if len(pk) == 0 {
+		return i18n.Errorf("la table %s n'a pas de clé primaire, snapshot impossible", table)
+	}
+
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	if cp == nil || cp.Table != table {
+		cp = &snapshotCheckpoint{Table: table}
+	} else {
+		log.Info("Reprise du snapshot de %s après la clé %v (%d lignes déjà envoyées)", table, cp.LastKey, cp.Rows)
+	}
+
+	log.Info("Snapshot démarré sur la table %s (clé: %s, tranches de %d lignes)", table, strings.Join(pk, ","), chunkSize)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		gate.open(pk)
+		rows, lastKey, err := reader.readChunk(ctx, pk, cp.LastKey, chunkSize)
+		if err != nil {
+			gate.close()
+			return i18n.Errorf("erreur lecture tranche: %w", err)
+		}
+		if lastKey == nil {
+			gate.close()
+			break
+		}
+
+		events := make([]*notifier.ChangeEvent, len(rows))
+		for i, row := range rows {
+			event := &notifier.ChangeEvent{
+				Operation: notifier.OpSnapshot,
+				Table:     table,
+				Timestamp: time.Now(),
+				Data:      row,
+			}
+			types.apply(event)
+			stamp(event, source, "")
+			events[i] = event
+		}
+
+		queued, err := gate.flush(ctx, events, workers)
+		if err != nil {
+			return i18n.Errorf("erreur mise en file snapshot: %w", err)
+		}
+		if err := workers.awaitSnapshot(ctx); err != nil {
+			return i18n.Errorf("erreur notification snapshot: %w", err)
+		}
+
+		cp.LastKey = lastKey
+		cp.Rows += int64(queued)
+		cp.UpdatedAt = time.Now()
+		if err := saveCheckpoint(path, cp); err != nil {
+			return err
+		}
+
+		log.Debug("Tranche de snapshot envoyée: %d lignes, dernière clé %v", queued, lastKey)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Erreur suppression checkpoint %s: %v", path, err)
+	}
+
+	log.Info("Snapshot terminé sur la table %s: %d lignes envoyées", table, cp.Rows)
+	return nil
+}
+
+func loadCheckpoint(path string) (*snapshotCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, i18n.Errorf("erreur lecture checkpoint: %w", err)
+	}
+
+	var cp snapshotCheckpoint
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&cp); err != nil {
+		return nil, i18n.Errorf("checkpoint %s illisible: %w", path, err)
+	}
+	return &cp, nil
+}
+
+func saveCheckpoint(path string, cp *snapshotCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return i18n.Errorf("erreur écriture checkpoint: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ResetSnapshot supprime le checkpoint pour repartir du début de la table.
+func ResetSnapshot(cfg *config.Config) error {
+	path := cfg.Listener.SnapshotCheckpoint
+	if path == "" {
+		path = defaultSnapshotCheckpoint
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// snapshotGate fait passer les lignes du snapshot par la file du listener,
+// comme les événements du flux. Pendant la lecture d'une tranche, il note
+// les clés des événements du flux mis en file: les lignes de ces clés sont
+// retirées de la tranche, l'événement du flux étant au moins aussi récent.
+// Les lignes restantes sont mises en file d'un bloc, avant tout événement
+// suivant du flux.
+type snapshotGate struct {
+	mu    sync.Mutex
+	queue *queue.Queue
+	pk    []string
+	seen  map[string]bool // nil hors tranche
+}
+
+func newSnapshotGate(q *queue.Queue) *snapshotGate {
+	return &snapshotGate{queue: q}
+}
+
+// push met en file un événement du flux.
+func (g *snapshotGate) push(ctx context.Context, event *notifier.ChangeEvent) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.seen != nil {
+		if event.Data != nil {
+			g.seen[rowKey(event.Data, g.pk)] = true
+		}
+		if event.OldData != nil {
+			g.seen[rowKey(event.OldData, g.pk)] = true
+		}
+	}
+	return g.queue.Push(ctx, event)
+}
+
+// open commence à noter les clés, avant le watermark bas de la tranche.
+func (g *snapshotGate) open(pk []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pk = pk
+	g.seen = make(map[string]bool)
+}
+
+func (g *snapshotGate) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seen = nil
+}
+
+// flush met en file les lignes de la tranche dont la clé n'a pas été vue
+// dans le flux et renvoie leur nombre.
+func (g *snapshotGate) flush(ctx context.Context, events []*notifier.ChangeEvent, workers *workerPool) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	queued := 0
+	for _, event := range events {
+		if g.seen[rowKey(event.Data, g.pk)] {
+			continue
+		}
+		workers.expectSnapshot()
+		if err := g.queue.Push(ctx, event); err != nil {
+			workers.snapshotDone(nil)
+			g.seen = nil
+			return queued, err
+		}
+		queued++
+	}
+	g.seen = nil
+	return queued, nil
+}
+
+// chunkWindow collecte les lignes d'une tranche, indexées par clé primaire,
+// pour pouvoir retirer celles modifiées pendant la fenêtre de watermarks.
+type chunkWindow struct {
+	pk   []string
+	keys []string
+	rows map[string]map[string]interface{}
+}
+
+func newChunkWindow(pk []string) *chunkWindow {
+	return &chunkWindow{pk: pk, rows: make(map[string]map[string]interface{})}
+}
+
+func (w *chunkWindow) add(row map[string]interface{}) {
+	key := rowKey(row, w.pk)
+	w.keys = append(w.keys, key)
+	w.rows[key] = row
+}
+
+// conflict retire de la tranche les lignes touchées par un événement du flux.
+func (w *chunkWindow) conflict(event *notifier.ChangeEvent) {
+	if event.Data != nil {
+		delete(w.rows, rowKey(event.Data, w.pk))
+	}
+	if event.OldData != nil {
+		delete(w.rows, rowKey(event.OldData, w.pk))
+	}
+}
+
+func (w *chunkWindow) result() []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(w.rows))
+	for _, key := range w.keys {
+		if row, ok := w.rows[key]; ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// decodeRow garde les nombres sous forme textuelle pour ne pas perdre de
+// précision sur les clés utilisées dans les requêtes suivantes.
+func decodeRow(data []byte) (map[string]interface{}, error) {
+	var row map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// decodeEvent décode une notification de trigger, avec les mêmes règles que
+// decodeRow pour les nombres.
+func decodeEvent(data []byte) (*notifier.ChangeEvent, error) {
+	var event notifier.ChangeEvent
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func rowKey(row map[string]interface{}, pk []string) string {
+	parts := make([]string, len(pk))
+	for i, col := range pk {
+		parts[i] = fmt.Sprint(row[col])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func keyValues(row map[string]interface{}, pk []string) []interface{} {
+	values := make([]interface{}, len(pk))
+	for i, col := range pk {
+		values[i] = row[col]
+	}
+	return values
+}
+
+func newWatermark() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
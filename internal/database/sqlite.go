@@ -0,0 +1,278 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+)
+
+// SQLiteListener lit par polling la table d'audit remplie par les triggers.
+// sqlite3_update_hook ne voit que les écritures de sa propre connexion: il ne
+// convient pas aux bases modifiées par une autre application.
+type SQLiteListener struct {
+	*sqliteObjects
+	notifier *notifier.Notifier
+	queue    *queue.Queue
+	gate     *snapshotGate
+	workers  *workerPool
+	types    *tableTypes
+}
+
+func NewSQLiteListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (*SQLiteListener, error) {
+	objects, err := openSQLiteObjects(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.ManageTriggers {
+		err = objects.Install(context.Background(), false)
+	} else {
+		err = objects.Verify(context.Background())
+	}
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur setup audit: %w", err)
+	}
+
+	columns, err := objects.columns(context.Background())
+	if err != nil {
+		objects.Close()
+		return nil, err
+	}
+
+	q, err := queue.New(&cfg.Worker, log)
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur création file d'événements: %w", err)
+	}
+
+	return &SQLiteListener{
+		sqliteObjects: objects,
+		notifier:      ntf,
+		queue:         q,
+		gate:          newSnapshotGate(q),
+		workers:       newWorkerPool(q, ntf, log),
+		types:         sqliteTypes(columns),
+	}, nil
+}
+
+func sqliteTypes(columns []column) *tableTypes {
+	fields := make([]notifier.Field, len(columns))
+	for i, c := range columns {
+		fields[i] = notifier.Field{
+			Name:   c.name,
+			Type:   sqliteType(c.columnType),
+			DBType: c.columnType,
+		}
+	}
+	return newTableTypes(fields)
+}
+
+func (sl *SQLiteListener) Listen(ctx context.Context) error {
+	sl.logger.Info("Écoute démarrée sur la table: %s (polling toutes les %d à %d secondes, lots de %d lignes)",
+		sl.config.Database.Table, sl.config.Listener.PollInterval, sl.config.Listener.MaxPollInterval, sl.config.Listener.BatchSize)
+
+	sl.workers.start(ctx, sl.config.Worker.PoolSize)
+
+	go sl.runJanitor(ctx)
+
+	delay := minPollDelay(&sl.config.Listener)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			sl.logger.Info("Arrêt de l'écoute")
+			return ctx.Err()
+		case <-timer.C:
+			n, err := sl.pollChanges(ctx)
+			if err != nil {
+				sl.logger.Error("Erreur polling: %v", err)
+			}
+			delay = nextPollDelay(&sl.config.Listener, delay, n, err)
+			timer.Reset(delay)
+		}
+	}
+}
+
+// pollChanges lit un lot de lignes non traitées et renvoie le nombre de
+// lignes mises en file. SQLite sérialise les écritures: les id sont validées
+// dans l'ordre, aucune ligne ne peut apparaître derrière celles déjà lues.
+func (sl *SQLiteListener) pollChanges(ctx context.Context) (int, error) {
+	rows, err := sl.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, operation, table_name, changed_at, data, old_data
+		FROM %s
+		WHERE processed = 0
+		ORDER BY id ASC
+		LIMIT %d
+	`, sl.auditTable(), sl.config.Listener.BatchSize))
+	if err != nil {
+		return 0, i18n.Errorf("erreur query audit: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var (
+			id        int64
+			operation string
+			tableName string
+			changedAt time.Time
+			data      string
+			oldData   sql.NullString
+		)
+
+		// Une ligne illisible est marquée comme traitée pour ne pas être relue
+		// à chaque polling; elle reste dans la table d'audit.
+		err := rows.Scan(&id, &operation, &tableName, &changedAt, &data, &oldData)
+		var dataMap map[string]interface{}
+		if err == nil {
+			dataMap, err = decodeRow([]byte(data))
+		}
+		if err != nil {
+			sl.logger.Error("Ligne d'audit %d illisible, marquée comme traitée: %v", id, err)
+			ids = append(ids, id)
+			continue
+		}
+
+		event := &notifier.ChangeEvent{
+			Operation: operation,
+			Table:     tableName,
+			Timestamp: changedAt,
+			Data:      dataMap,
+		}
+		if oldData.Valid {
+			if oldDataMap, err := decodeRow([]byte(oldData.String)); err == nil {
+				event.OldData = oldDataMap
+			}
+		}
+		sl.types.apply(event)
+		stamp(event, eventSource(sl.config, sl.table), strconv.FormatInt(id, 10))
+
+		// Un événement refusé n'est pas marqué: il sera relu au prochain
+		// polling, avec les suivants.
+		if err := sl.gate.push(ctx, event); err != nil {
+			if ctx.Err() == nil {
+				sl.logger.With(logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID).
+					Warn("Événement %d non mis en file (%s): %v", id, sl.queue.Policy(), err)
+			}
+			break
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		if err := sl.markProcessed(ctx, ids); err != nil {
+			sl.logger.Error("Erreur marquage processed: %v", err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+func (sl *SQLiteListener) markProcessed(ctx context.Context, ids []int64) error {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	_, err := sl.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET processed = 1 WHERE id IN (%s)", sl.auditTable(), placeholders), args...)
+	return err
+}
+
+// runJanitor supprime régulièrement les lignes d'audit traitées plus
+// anciennes que audit.retention_hours.
+func (sl *SQLiteListener) runJanitor(ctx context.Context) {
+	cfg := sl.config.Audit
+
+	ticker := time.NewTicker(time.Duration(cfg.CleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if cfg.RetentionHours > 0 {
+			if err := sl.cleanupAudit(ctx); err != nil && ctx.Err() == nil {
+				sl.logger.Error("Erreur nettoyage de la table d'audit: %v", err)
+			}
+		}
+		if err := sl.auditStats(ctx); err != nil && ctx.Err() == nil {
+			sl.logger.Warn("Erreur lecture statistiques d'audit: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanupAudit supprime par lots pour ne pas bloquer les écritures de
+// l'application, SQLite n'ayant qu'un seul écrivain à la fois.
+func (sl *SQLiteListener) cleanupAudit(ctx context.Context) error {
+	cfg := sl.config.Audit
+	cutoff := time.Now().UTC().Add(-time.Duration(cfg.RetentionHours) * time.Hour)
+
+	var total int64
+	for {
+		res, err := sl.db.ExecContext(ctx, fmt.Sprintf(`
+			DELETE FROM %s WHERE id IN (
+				SELECT id FROM %s WHERE processed = 1 AND changed_at < ? LIMIT %d
+			)`, sl.auditTable(), sl.auditTable(), cfg.BatchSize), cutoff.Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < int64(cfg.BatchSize) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if total > 0 {
+		metrics.AuditPurged.Add(total)
+		sl.logger.Info("Table d'audit: %d lignes traitées antérieures au %s supprimées", total, cutoff.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (sl *SQLiteListener) auditStats(ctx context.Context) error {
+	var rows, backlog int64
+	err := sl.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT COUNT(*), COALESCE(SUM(processed = 0), 0) FROM %s", sl.auditTable())).Scan(&rows, &backlog)
+	if err != nil {
+		return err
+	}
+	metrics.AuditRows.Set(rows)
+	metrics.AuditBacklog.Set(backlog)
+	return nil
+}
+
+// SetPoolSize ajuste le nombre de workers sans interrompre l'écoute.
+func (sl *SQLiteListener) SetPoolSize(size int) {
+	sl.workers.resize(size)
+}
+
+func (sl *SQLiteListener) Close() error {
+	if sl.queue != nil {
+		sl.queue.Close()
+	}
+	return sl.db.Close()
+}
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/sqlident"
+)
+
+const lite = sqlident.SQLite
+
+// sqliteObjects génère et gère la table d'audit et les triggers d'une table
+// SQLite. Comme pour MySQL, le marqueur de propriété est stocké dans
+// paypayo_objects.
+type sqliteObjects struct {
+	db     *sql.DB
+	table  sqlident.Name
+	config *config.Config
+	logger *logger.Logger
+}
+
+// sqliteDSN attend jusqu'à 5 secondes qu'un autre processus libère la base
+// plutôt que d'échouer aussitôt avec SQLITE_BUSY.
+func sqliteDSN(cfg *config.Config) string {
+	return "file:" + cfg.Database.Database + "?_busy_timeout=5000"
+}
+
+func openSQLiteObjects(cfg *config.Config, log *logger.Logger) (*sqliteObjects, error) {
+	table, err := sqlident.ParseName(cfg.Database.Table)
+	if err == nil {
+		err = table.Validate(lite)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("nom de table invalide: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(cfg))
+	if err != nil {
+		return nil, i18n.Errorf("erreur ouverture SQLite: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, i18n.Errorf("erreur ping SQLite: %w", err)
+	}
+
+	return &sqliteObjects{db: db, table: table, config: cfg, logger: log.With(logger.FieldTable, table.String())}, nil
+}
+
+func (o *sqliteObjects) auditTable() string {
+	return lite.QuoteName(o.table.WithSuffix("_audit"))
+}
+
+func (o *sqliteObjects) triggerName(op string) sqlident.Name {
+	return o.table.WithSuffix("_" + op + "_trigger")
+}
+
+func (o *sqliteObjects) registry() string {
+	return lite.Quote(registryTable)
+}
+
+func (o *sqliteObjects) plan(ctx context.Context) ([]dbObject, error) {
+	auditTable := o.auditTable()
+	table := lite.QuoteName(o.table)
+
+	objects := []dbObject{
+		{
+			kind: "TABLE",
+			name: o.table.WithSuffix("_audit").String(),
+			create: []string{fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			operation TEXT NOT NULL,
+			table_name TEXT NOT NULL,
+			changed_at TIMESTAMP DEFAULT (strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now')),
+			data TEXT,
+			old_data TEXT,
+			processed INTEGER NOT NULL DEFAULT 0
+		)`, auditTable)},
+			drop:     "DROP TABLE " + auditTable,
+			enabled:  true,
+			preserve: true,
+		},
+		{
+			kind:    "INDEX",
+			name:    o.table.WithSuffix("_audit").String() + "." + pollIndex,
+			create:  []string{fmt.Sprintf("CREATE INDEX %s ON %s (processed, id)", lite.Quote(o.pollIndex()), auditTable)},
+			drop:    "DROP INDEX " + lite.Quote(o.pollIndex()),
+			enabled: true,
+		},
+	}
+
+	columns, err := o.columns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newColumns := sqliteJSONArgs(columns, "NEW")
+	oldColumns := sqliteJSONArgs(columns, "OLD")
+
+	triggers := []struct {
+		op      string
+		enabled bool
+		columns string
+		values  string
+	}{
+		{"insert", o.config.Listener.IsInsertEnabled(), "data", fmt.Sprintf("json_object(%s)", newColumns)},
+		{"update", o.config.Listener.IsUpdateEnabled(), "data, old_data", fmt.Sprintf("json_object(%s), json_object(%s)", newColumns, oldColumns)},
+		{"delete", o.config.Listener.IsDeleteEnabled(), "data", fmt.Sprintf("json_object(%s)", oldColumns)},
+	}
+
+	for _, t := range triggers {
+		op := strings.ToUpper(t.op)
+		name := lite.QuoteName(o.triggerName(t.op))
+
+		objects = append(objects, dbObject{
+			kind: "TRIGGER",
+			name: o.triggerName(t.op).String(),
+			create: []string{fmt.Sprintf(`
+			CREATE TRIGGER %s
+			AFTER %s ON %s
+			FOR EACH ROW
+			BEGIN
+				INSERT INTO %s (operation, table_name, %s)
+				VALUES ('%s', %s, %s);
+			END`, name, op, table, auditTable, t.columns, op, lite.Literal(o.table.String()), t.values)},
+			drop:    "DROP TRIGGER " + name,
+			enabled: t.enabled,
+		})
+	}
+
+	return objects, nil
+}
+
+// pollIndex est préfixé par la table d'audit: les index SQLite partagent un
+// espace de noms unique par base.
+func (o *sqliteObjects) pollIndex() string {
+	return o.table.Name + "_audit_" + pollIndex
+}
+
+// columns lit les colonnes de la table et leur type déclaré.
+func (o *sqliteObjects) columns(ctx context.Context) ([]column, error) {
+	rows, err := o.db.QueryContext(ctx, "SELECT name, type FROM pragma_table_info(?) ORDER BY cid", o.table.Name)
+	if err != nil {
+		return nil, i18n.Errorf("erreur récupération colonnes: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.columnType); err != nil {
+			return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+		}
+		c.dataType = c.columnType
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, i18n.Errorf("aucune colonne trouvée pour la table %s", o.table)
+	}
+
+	return columns, nil
+}
+
+// sqliteJSONArgs renvoie les paires attendues par json_object. json_object
+// refuse les BLOB: ils sont passés en hexadécimal préfixé de \x, comme
+// PostgreSQL. Un texte JSON valide est inclus tel quel dans l'objet.
+func sqliteJSONArgs(columns []column, prefix string) string {
+	pairs := make([]string, len(columns))
+	for i, c := range columns {
+		ref := prefix + "." + lite.Quote(c.name)
+		value := fmt.Sprintf(`CASE WHEN typeof(%s) = 'blob' THEN '\x' || hex(%s) ELSE %s END`, ref, ref, ref)
+		if sqliteType(c.columnType) == typeJSON {
+			value = fmt.Sprintf("CASE WHEN json_valid(%s) THEN json(%s) ELSE %s END", ref, ref, ref)
+		}
+		pairs[i] = fmt.Sprintf("%s, %s", lite.Literal(c.name), value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (o *sqliteObjects) state(ctx context.Context, obj dbObject) (objectState, error) {
+	name := obj.name
+	if obj.kind == "INDEX" {
+		name = o.pollIndex()
+	}
+
+	var count int
+	err := o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?",
+		strings.ToLower(obj.kind), name).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return stateMissing, nil
+	}
+
+	var registered int
+	if err := o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		registryTable).Scan(&registered); err != nil {
+		return 0, err
+	}
+	if registered == 0 {
+		return stateForeign, nil
+	}
+
+	var marker string
+	err = o.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT marker FROM %s WHERE object_type = ? AND object_name = ?", o.registry()),
+		obj.kind, obj.name).Scan(&marker)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return stateForeign, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return markerState(true, marker, obj.marker()), nil
+}
+
+func (o *sqliteObjects) registryDDL() string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			object_type TEXT NOT NULL,
+			object_name TEXT NOT NULL,
+			marker TEXT NOT NULL,
+			installed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (object_type, object_name)
+		)`, o.registry())
+}
+
+func (o *sqliteObjects) script(obj dbObject) []string {
+	register := fmt.Sprintf("INSERT OR REPLACE INTO %s (object_type, object_name, marker) VALUES (%s, %s, %s)",
+		o.registry(), lite.Literal(obj.kind), lite.Literal(obj.name), lite.Literal(obj.marker()))
+	return append(append([]string{}, obj.create...), register)
+}
+
+// apply crée l'objet et l'enregistre dans une seule transaction: le DDL
+// SQLite est transactionnel, un trigger remplacé ne laisse passer aucune
+// écriture.
+func (o *sqliteObjects) apply(ctx context.Context, obj dbObject, replace bool) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, o.registryDDL()); err != nil {
+		return i18n.Errorf("erreur création registre %s: %w", registryTable, err)
+	}
+	if replace && !obj.preserve {
+		if _, err := tx.ExecContext(ctx, obj.drop); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range o.script(obj) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (o *sqliteObjects) remove(ctx context.Context, obj dbObject) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, obj.drop); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE object_type = ? AND object_name = ?", o.registry()),
+		obj.kind, obj.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (o *sqliteObjects) Install(ctx context.Context, adopt bool) error {
+	return installObjects(ctx, o, o.logger, adopt)
+}
+
+// Uninstall supprime aussi le registre une fois vide.
+func (o *sqliteObjects) Uninstall(ctx context.Context) error {
+	if err := uninstallObjects(ctx, o, o.logger); err != nil {
+		return err
+	}
+
+	var remaining int
+	err := o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+o.registry()).Scan(&remaining)
+	if err != nil {
+		// Registre absent: rien n'était installé
+		return nil
+	}
+	if remaining == 0 {
+		if _, err := o.db.ExecContext(ctx, "DROP TABLE "+o.registry()); err != nil {
+			return err
+		}
+		o.logger.Info("Registre %s supprimé", registryTable)
+	}
+	return nil
+}
+
+func (o *sqliteObjects) Verify(ctx context.Context) error {
+	return verifyObjects(ctx, o, o.logger)
+}
+
+func (o *sqliteObjects) GenerateSQL(ctx context.Context, w io.Writer) error {
+	header := i18n.Sprintf("Objets paypayo pour la table %s (modes: %s)", o.table, o.config.Listener.Modes)
+	return writeScript(ctx, w, o, header, []string{o.registryDDL()})
+}
+
+func (o *sqliteObjects) Close() error {
+	return o.db.Close()
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/notifier"
+)
+
+// Snapshot envoie le contenu actuel de la table sous forme d'événements
+// SNAPSHOT, en reprenant au dernier checkpoint s'il existe.
+func (sl *SQLiteListener) Snapshot(ctx context.Context) error {
+	return runSnapshot(ctx, &sqliteChunkReader{sl: sl}, eventSource(sl.config, sl.table), sl.types, sl.config, sl.logger, sl.gate, sl.workers)
+}
+
+// sqliteChunkReader reprend l'approche de MySQL: watermarks écrits dans la
+// table d'audit déjà marqués comme traités, fenêtre de conflit entre les
+// deux.
+type sqliteChunkReader struct {
+	sl *SQLiteListener
+}
+
+func (r *sqliteChunkReader) primaryKey(ctx context.Context) ([]string, error) {
+	rows, err := r.sl.db.QueryContext(ctx,
+		"SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", r.sl.table.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pk []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		pk = append(pk, col)
+	}
+	return pk, rows.Err()
+}
+
+func (r *sqliteChunkReader) readChunk(ctx context.Context, pk []string, after []interface{}, limit int) ([]map[string]interface{}, []interface{}, error) {
+	low, err := r.insertWatermark(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	window := newChunkWindow(pk)
+	var lastKey []interface{}
+
+	query, args, err := r.chunkQuery(ctx, pk, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := r.sl.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		row, err := decodeRow([]byte(raw))
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		window.add(row)
+		lastKey = keyValues(row, pk)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	high, err := r.insertWatermark(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.applyWindow(ctx, low, high, window); err != nil {
+		return nil, nil, err
+	}
+
+	return window.result(), lastKey, nil
+}
+
+func (r *sqliteChunkReader) applyWindow(ctx context.Context, low, high int64, window *chunkWindow) error {
+	auditTable := r.sl.auditTable()
+
+	rows, err := r.sl.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT data, old_data FROM %s
+		WHERE id > ? AND id < ? AND operation <> ?
+	`, auditTable), low, high, notifier.OpWatermark)
+	if err != nil {
+		return i18n.Errorf("erreur lecture fenêtre de watermarks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		var oldData sql.NullString
+		if err := rows.Scan(&data, &oldData); err != nil {
+			return err
+		}
+
+		var event notifier.ChangeEvent
+		event.Data, _ = decodeRow([]byte(data))
+		if oldData.Valid {
+			event.OldData, _ = decodeRow([]byte(oldData.String))
+		}
+		window.conflict(&event)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = r.sl.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id IN (?, ?)", auditTable), low, high)
+	return err
+}
+
+func (r *sqliteChunkReader) insertWatermark(ctx context.Context) (int64, error) {
+	res, err := r.sl.db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (operation, table_name, data, processed)
+		VALUES (?, ?, json_object('watermark', ?), 1)
+	`, r.sl.auditTable()), notifier.OpWatermark, r.sl.table.String(), newWatermark())
+	if err != nil {
+		return 0, i18n.Errorf("erreur écriture watermark: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *sqliteChunkReader) chunkQuery(ctx context.Context, pk []string, after []interface{}, limit int) (string, []interface{}, error) {
+	columns, err := r.sl.columns(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cols := lite.QuoteList(pk)
+	selectSQL := fmt.Sprintf("SELECT json_object(%s) FROM %s t", sqliteJSONArgs(columns, "t"), lite.QuoteName(r.sl.table))
+
+	if after == nil {
+		return fmt.Sprintf("%s ORDER BY %s LIMIT %d", selectSQL, cols, limit), nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(pk)), ", ")
+	return fmt.Sprintf("%s WHERE (%s) > (%s) ORDER BY %s LIMIT %d", selectSQL, cols, placeholders, cols, limit), after, nil
+}
+
+func (r *sqliteChunkReader) close() error {
+	return nil
+}
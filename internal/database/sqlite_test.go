@@ -0,0 +1,343 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+)
+
+// newWebhook démarre un webhook de test qui transmet les événements reçus.
+func newWebhook(t *testing.T) (string, <-chan notifier.ChangeEvent) {
+	t.Helper()
+	events := make(chan notifier.ChangeEvent, 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event notifier.ChangeEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("corps illisible: %v: %s", err, body)
+		}
+		events <- event
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, events
+}
+
+// receive attend n événements du webhook. Le délai couvre la latence de la
+// capture CDC de SQL Server.
+func receive(t *testing.T, events <-chan notifier.ChangeEvent, n int) []notifier.ChangeEvent {
+	t.Helper()
+	var got []notifier.ChangeEvent
+	for len(got) < n {
+		select {
+		case event := <-events:
+			got = append(got, event)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("%d événements reçus, %d attendus", len(got), n)
+		}
+	}
+	return got
+}
+
+func newSQLiteTest(t *testing.T, webhook string) (*config.Config, *sql.DB, *logger.Logger, *notifier.Notifier) {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Database.Type = "sqlite"
+	cfg.Database.Database = filepath.Join(dir, "test.db")
+	cfg.Database.Table = "users"
+	cfg.Listener.PollInterval = 1
+	cfg.Listener.MaxPollInterval = 1
+	cfg.Listener.SnapshotChunkSize = 10
+	cfg.Listener.SnapshotCheckpoint = filepath.Join(dir, "snapshot.json")
+	cfg.Worker.PoolSize = 1
+	cfg.Webhook.URL = webhook
+	cfg.Webhook.Timeout = 5
+	cfg.Logging.File = filepath.Join(dir, "test.log")
+	cfg.Logging.Level = "debug"
+
+	db, err := sql.Open("sqlite3", sqliteDSN(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, meta JSON)`); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := logger.New(&cfg.Logging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ntf, err := notifier.New(&cfg.Webhook, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, db, log, ntf
+}
+
+// sqliteMaster renvoie le nombre d'objets de ce type et de ce nom.
+func sqliteMaster(t *testing.T, db *sql.DB, kind, name string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?", kind, name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSQLiteInstallUninstall(t *testing.T) {
+	cfg, db, log, _ := newSQLiteTest(t, "http://localhost")
+	cfg.Listener.Modes = "insert,update"
+	ctx := context.Background()
+
+	o, err := openSQLiteObjects(cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if err := o.Verify(ctx); err == nil {
+		t.Fatal("Verify sans objets installés: erreur attendue")
+	}
+	if err := o.Install(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	// Une seconde installation ne modifie rien.
+	if err := o.Install(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := []struct {
+		kind, name string
+		want       int
+	}{
+		{"table", "users_audit", 1},
+		{"table", registryTable, 1},
+		{"index", o.pollIndex(), 1},
+		{"trigger", "users_insert_trigger", 1},
+		{"trigger", "users_update_trigger", 1},
+		{"trigger", "users_delete_trigger", 0},
+	}
+	for _, obj := range objects {
+		if got := sqliteMaster(t, db, obj.kind, obj.name); got != obj.want {
+			t.Errorf("%s %s: %d, attendu %d", obj.kind, obj.name, got, obj.want)
+		}
+	}
+
+	if _, err := db.Exec(`INSERT INTO users (name, meta) VALUES ('o''brien', '{"a":1}')`); err != nil {
+		t.Fatal(err)
+	}
+	var data string
+	if err := db.QueryRow("SELECT data FROM users_audit WHERE operation = 'INSERT'").Scan(&data); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":1,"name":"o'brien","meta":{"a":1}}`; data != want {
+		t.Errorf("data = %s, attendu %s", data, want)
+	}
+
+	// Un trigger homonyme créé hors paypayo n'est ni remplacé ni supprimé.
+	if _, err := db.Exec(`CREATE TRIGGER users_delete_trigger AFTER DELETE ON users BEGIN SELECT 1; END`); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Listener.Modes = "insert,update,delete"
+	if err := o.Install(ctx, false); err == nil {
+		t.Error("Install sur trigger étranger: erreur attendue")
+	}
+
+	if err := o.Uninstall(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objects[:5] {
+		if got := sqliteMaster(t, db, obj.kind, obj.name); got != 0 {
+			t.Errorf("%s %s conservé après désinstallation", obj.kind, obj.name)
+		}
+	}
+	if got := sqliteMaster(t, db, "trigger", "users_delete_trigger"); got != 1 {
+		t.Error("trigger étranger supprimé")
+	}
+}
+
+func TestSQLiteListen(t *testing.T) {
+	url, events := newWebhook(t)
+	cfg, db, log, ntf := newSQLiteTest(t, url)
+
+	l, err := NewListener(cfg, log, ntf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Listen(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, stmt := range []string{
+		`INSERT INTO users (name, meta) VALUES ('alice', '{"vip":true}')`,
+		`UPDATE users SET name = 'bob' WHERE id = 1`,
+		`DELETE FROM users WHERE id = 1`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := receive(t, events, 3)
+	want := []struct {
+		operation string
+		name      string
+		oldName   string
+	}{
+		{"INSERT", "alice", ""},
+		{"UPDATE", "bob", "alice"},
+		{"DELETE", "bob", ""},
+	}
+	for i, w := range want {
+		event := got[i]
+		if event.Operation != w.operation || event.Table != "users" || event.Data["name"] != w.name {
+			t.Errorf("événement %d = %s %s %v, attendu %s %s", i, event.Operation, event.Table, event.Data, w.operation, w.name)
+		}
+		if w.oldName != "" && event.OldData["name"] != w.oldName {
+			t.Errorf("événement %d: old_data = %v, attendu name=%s", i, event.OldData, w.oldName)
+		}
+	}
+	if meta, ok := got[0].Data["meta"].(map[string]interface{}); !ok || meta["vip"] != true {
+		t.Errorf("meta = %#v, objet JSON attendu", got[0].Data["meta"])
+	}
+
+	// Les lignes envoyées sont marquées une fois mises en file.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var pending int
+		if err := db.QueryRow("SELECT COUNT(*) FROM users_audit WHERE processed = 0").Scan(&pending); err != nil {
+			t.Fatal(err)
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d lignes d'audit non marquées", pending)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSQLitePollUnreadable(t *testing.T) {
+	url, events := newWebhook(t)
+	cfg, db, log, ntf := newSQLiteTest(t, url)
+
+	l, err := NewSQLiteListener(cfg, log, ntf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := db.Exec(`INSERT INTO users_audit (operation, table_name, data) VALUES ('INSERT', 'users', 'pas du json')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (name) VALUES ('alice')`); err != nil {
+		t.Fatal(err)
+	}
+
+	// La ligne illisible est marquée sans bloquer la suivante.
+	n, err := l.pollChanges(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("pollChanges = %d, attendu 2", n)
+	}
+	var pending int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users_audit WHERE processed = 0").Scan(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d lignes d'audit non marquées", pending)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.workers.start(ctx, 1)
+	if got := receive(t, events, 1); got[0].Data["name"] != "alice" {
+		t.Errorf("événement = %v", got[0].Data)
+	}
+}
+
+func TestSQLiteSnapshotCheckpoint(t *testing.T) {
+	url, events := newWebhook(t)
+	cfg, db, log, ntf := newSQLiteTest(t, url)
+	cfg.Worker.PoolSize = 4
+
+	for i := 0; i < 25; i++ {
+		if _, err := db.Exec(`INSERT INTO users (name) VALUES ('user')`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reprise après une première tranche déjà envoyée.
+	checkpoint := `{"table":"users","last_key":[10],"rows":10}`
+	if err := os.WriteFile(cfg.Listener.SnapshotCheckpoint, []byte(checkpoint), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewListener(cfg, log, ntf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := l.Snapshot(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshot attend l'envoi de chaque tranche avant de rendre la main.
+	seen := make(map[float64]bool)
+	for _, event := range receive(t, events, 15) {
+		if event.Operation != notifier.OpSnapshot {
+			t.Errorf("opération %s, attendu %s", event.Operation, notifier.OpSnapshot)
+		}
+		id, _ := event.Data["id"].(float64)
+		if id <= 10 || seen[id] {
+			t.Errorf("ligne %v envoyée à tort", event.Data["id"])
+		}
+		seen[id] = true
+	}
+	select {
+	case event := <-events:
+		t.Errorf("événement en trop: %s %v", event.Operation, event.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := os.Stat(cfg.Listener.SnapshotCheckpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint conservé après un snapshot complet: %v", err)
+	}
+
+	// Les watermarks sont retirés de la table d'audit.
+	var watermarks int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users_audit WHERE operation = ?", notifier.OpWatermark).Scan(&watermarks); err != nil {
+		t.Fatal(err)
+	}
+	if watermarks != 0 {
+		t.Errorf("%d watermarks restés dans la table d'audit", watermarks)
+	}
+}
@@ -0,0 +1,339 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+)
+
+// Valeurs de __$operation dans les tables de changements CDC.
+const (
+	cdcDelete       = 1
+	cdcInsert       = 2
+	cdcUpdateBefore = 3
+	cdcUpdateAfter  = 4
+)
+
+// SQLServerListener lit par polling les changements capturés par CDC. La
+// capture est asynchrone (job de l'Agent SQL Server): les changements
+// apparaissent quelques secondes après leur validation.
+type SQLServerListener struct {
+	*sqlserverObjects
+	notifier *notifier.Notifier
+	queue    *queue.Queue
+	gate     *snapshotGate
+	workers  *workerPool
+	types    *tableTypes
+	// Colonnes capturées, dans l'ordre de la table.
+	captured []column
+	// Dernier changement mis en file: LSN de la transaction et numéro de
+	// séquence dans celle-ci.
+	lastLSN []byte
+	lastSeq []byte
+}
+
+// cdcChange est une ligne de la table de changements.
+type cdcChange struct {
+	lsn       []byte
+	seq       []byte
+	operation int
+	timestamp time.Time
+	data      map[string]interface{}
+}
+
+func NewSQLServerListener(cfg *config.Config, log *logger.Logger, ntf *notifier.Notifier) (*SQLServerListener, error) {
+	objects, err := openSQLServerObjects(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.ManageTriggers {
+		err = objects.Install(context.Background(), false)
+	} else {
+		err = objects.Verify(context.Background())
+	}
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur setup CDC: %w", err)
+	}
+
+	sl := &SQLServerListener{sqlserverObjects: objects, notifier: ntf}
+	if err := sl.loadColumns(context.Background()); err != nil {
+		objects.Close()
+		return nil, err
+	}
+	if err := sl.loadOffset(context.Background()); err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur lecture position CDC: %w", err)
+	}
+
+	sl.queue, err = queue.New(&cfg.Worker, log)
+	if err != nil {
+		objects.Close()
+		return nil, i18n.Errorf("erreur création file d'événements: %w", err)
+	}
+	sl.gate = newSnapshotGate(sl.queue)
+	sl.workers = newWorkerPool(sl.queue, ntf, log)
+
+	return sl, nil
+}
+
+// loadColumns lit les colonnes de la table et ne garde que celles de
+// l'instance de capture.
+func (sl *SQLServerListener) loadColumns(ctx context.Context) error {
+	columns, err := sl.columns(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := sl.db.QueryContext(ctx, `
+		SELECT cc.column_name
+		FROM cdc.captured_columns cc
+		JOIN cdc.change_tables ct ON ct.object_id = cc.object_id
+		WHERE ct.capture_instance = @p1
+	`, sl.captureInstance())
+	if err != nil {
+		return i18n.Errorf("erreur lecture colonnes capturées: %w", err)
+	}
+	defer rows.Close()
+
+	captured := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return i18n.Errorf("erreur lecture colonnes capturées: %w", err)
+		}
+		captured[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return i18n.Errorf("erreur lecture colonnes capturées: %w", err)
+	}
+
+	fields := make([]notifier.Field, 0, len(columns))
+	for _, c := range columns {
+		if !captured[c.name] {
+			sl.logger.Warn("Colonne %s non capturée par %s, absente des événements", c.name, sl.captureInstance())
+			continue
+		}
+		sl.captured = append(sl.captured, c)
+		fields = append(fields, notifier.Field{
+			Name:   c.name,
+			Type:   sqlserverType(c.dataType),
+			DBType: c.columnType,
+		})
+	}
+	if len(sl.captured) == 0 {
+		return i18n.Errorf("instance de capture %s introuvable ou sans colonne", sl.captureInstance())
+	}
+	sl.types = newTableTypes(fields)
+	return nil
+}
+
+func (sl *SQLServerListener) loadOffset(ctx context.Context) error {
+	err := sl.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT last_lsn, last_seqval FROM %s WHERE capture_instance = @p1", sl.offsets()),
+		sl.captureInstance()).Scan(&sl.lastLSN, &sl.lastSeq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err == nil {
+		sl.logger.Info("Reprise de la capture %s après le LSN %s", sl.captureInstance(), formatLSN(sl.lastLSN))
+	}
+	return err
+}
+
+func (sl *SQLServerListener) saveOffset(ctx context.Context) error {
+	_, err := sl.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET last_lsn = @p2, last_seqval = @p3, updated_at = SYSUTCDATETIME() WHERE capture_instance = @p1;
+		IF @@ROWCOUNT = 0
+			INSERT INTO %s (capture_instance, last_lsn, last_seqval) VALUES (@p1, @p2, @p3)
+	`, sl.offsets(), sl.offsets()), sl.captureInstance(), sl.lastLSN, sl.lastSeq)
+	return err
+}
+
+func (sl *SQLServerListener) Listen(ctx context.Context) error {
+	sl.logger.Info("Écoute démarrée sur la table: %s (capture CDC %s, polling toutes les %d à %d secondes, lots de %d changements)",
+		sl.config.Database.Table, sl.captureInstance(), sl.config.Listener.PollInterval, sl.config.Listener.MaxPollInterval, sl.config.Listener.BatchSize)
+
+	sl.workers.start(ctx, sl.config.Worker.PoolSize)
+
+	delay := minPollDelay(&sl.config.Listener)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			sl.logger.Info("Arrêt de l'écoute")
+			return ctx.Err()
+		case <-timer.C:
+			n, err := sl.pollChanges(ctx)
+			if err != nil {
+				sl.logger.Error("Erreur polling: %v", err)
+			}
+			delay = nextPollDelay(&sl.config.Listener, delay, n, err)
+			timer.Reset(delay)
+		}
+	}
+}
+
+// pollChanges lit un lot de changements au-delà de la dernière position et
+// renvoie le nombre de changements lus. La position est enregistrée en base
+// après chaque lot: une autre instance (ha.mode leader) reprend au même point.
+func (sl *SQLServerListener) pollChanges(ctx context.Context) (int, error) {
+	from, seq, err := sl.startPosition(ctx)
+	if err != nil || from == nil {
+		return 0, err
+	}
+
+	// WITH TIES garde ensemble les deux lignes (avant, après) d'un UPDATE
+	query := fmt.Sprintf(`
+		SELECT TOP (%d) WITH TIES c.__$start_lsn, c.__$seqval, c.__$operation,
+			TODATETIMEOFFSET(sys.fn_cdc_map_lsn_to_time(c.__$start_lsn), DATEPART(TZOFFSET, SYSDATETIMEOFFSET())),
+			%s
+		FROM cdc.%s(@p1, sys.fn_cdc_get_max_lsn(), N'all update old') c
+		WHERE c.__$start_lsn > @p1 OR c.__$seqval > @p2
+		ORDER BY c.__$start_lsn, c.__$seqval
+	`, sl.config.Listener.BatchSize, jsonSelect(sl.captured, "c"), ms.Quote("fn_cdc_get_all_changes_"+sl.captureInstance()))
+
+	rows, err := sl.db.QueryContext(ctx, query, from, seq)
+	if err != nil {
+		return 0, i18n.Errorf("erreur lecture des changements CDC: %w", err)
+	}
+	defer rows.Close()
+
+	read := 0
+	var before *cdcChange
+	for rows.Next() {
+		c := &cdcChange{}
+		var data sql.NullString
+		if err := rows.Scan(&c.lsn, &c.seq, &c.operation, &c.timestamp, &data); err != nil {
+			return read, i18n.Errorf("erreur scan changement: %w", err)
+		}
+		if data.Valid {
+			if c.data, err = decodeRow([]byte(data.String)); err != nil {
+				sl.logger.Error("Erreur unmarshal data: %v", err)
+			}
+		}
+		read++
+
+		// Les deux lignes d'un UPDATE partagent LSN et séquence
+		var event *notifier.ChangeEvent
+		switch c.operation {
+		case cdcUpdateBefore, cdcUpdateAfter:
+			if before == nil || !bytes.Equal(before.lsn, c.lsn) || !bytes.Equal(before.seq, c.seq) {
+				before = c
+				continue
+			}
+			after := c
+			if c.operation == cdcUpdateBefore {
+				before, after = after, before
+			}
+			event = sl.newEvent("UPDATE", after, after.data, before.data)
+			before = nil
+		case cdcInsert:
+			event = sl.newEvent("INSERT", c, c.data, nil)
+		case cdcDelete:
+			event = sl.newEvent("DELETE", c, c.data, nil)
+		}
+
+		if event != nil && sl.enabled(event.Operation) {
+			if err := sl.gate.push(ctx, event); err != nil {
+				if ctx.Err() == nil {
+					sl.logger.With(logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID).
+						Warn("Changement %s non mis en file (%s): %v", formatLSN(c.lsn), sl.queue.Policy(), err)
+				}
+				read = 0
+				break
+			}
+		}
+		sl.lastLSN, sl.lastSeq = c.lsn, c.seq
+	}
+	if err := rows.Err(); err != nil {
+		return read, i18n.Errorf("erreur lecture des changements CDC: %w", err)
+	}
+	rows.Close()
+
+	if !bytes.Equal(sl.lastLSN, from) || !bytes.Equal(sl.lastSeq, seq) {
+		if err := sl.saveOffset(ctx); err != nil {
+			sl.logger.Error("Erreur enregistrement de la position CDC: %v", err)
+		}
+	}
+
+	return read, nil
+}
+
+// startPosition renvoie la position à partir de laquelle lire, ou nil s'il
+// n'y a encore rien à lire. Une position sortie de la rétention CDC (job de
+// nettoyage) est signalée: les changements intermédiaires sont perdus.
+func (sl *SQLServerListener) startPosition(ctx context.Context) ([]byte, []byte, error) {
+	var minLSN, maxLSN []byte
+	err := sl.db.QueryRowContext(ctx, "SELECT sys.fn_cdc_get_min_lsn(@p1), sys.fn_cdc_get_max_lsn()",
+		sl.captureInstance()).Scan(&minLSN, &maxLSN)
+	if err != nil {
+		return nil, nil, i18n.Errorf("erreur lecture des LSN CDC: %w", err)
+	}
+	// Capture pas encore passée (Agent SQL Server arrêté ?)
+	if maxLSN == nil || bytes.Equal(minLSN, make([]byte, 10)) {
+		return nil, nil, nil
+	}
+
+	if sl.lastLSN == nil || bytes.Compare(sl.lastLSN, minLSN) < 0 {
+		if sl.lastLSN != nil {
+			sl.logger.Warn("Position %s sortie de la rétention CDC, reprise au LSN %s: des changements ont été perdus",
+				formatLSN(sl.lastLSN), formatLSN(minLSN))
+		}
+		// Séquence nulle: la première transaction est lue en entier
+		return minLSN, make([]byte, 10), nil
+	}
+	if bytes.Compare(sl.lastLSN, maxLSN) > 0 {
+		return nil, nil, nil
+	}
+	return sl.lastLSN, sl.lastSeq, nil
+}
+
+func (sl *SQLServerListener) newEvent(operation string, c *cdcChange, data, oldData map[string]interface{}) *notifier.ChangeEvent {
+	event := &notifier.ChangeEvent{
+		Operation: operation,
+		Table:     sl.table.String(),
+		Timestamp: c.timestamp,
+		Data:      data,
+		OldData:   oldData,
+	}
+	sl.types.apply(event)
+	stamp(event, eventSource(sl.config, sl.table), formatLSN(c.lsn))
+	return event
+}
+
+// enabled applique listener.modes: CDC capture toutes les opérations.
+func (sl *SQLServerListener) enabled(operation string) bool {
+	switch operation {
+	case "INSERT":
+		return sl.config.Listener.IsInsertEnabled()
+	case "UPDATE":
+		return sl.config.Listener.IsUpdateEnabled()
+	default:
+		return sl.config.Listener.IsDeleteEnabled()
+	}
+}
+
+// SetPoolSize ajuste le nombre de workers sans interrompre l'écoute.
+func (sl *SQLServerListener) SetPoolSize(size int) {
+	sl.workers.resize(size)
+}
+
+func (sl *SQLServerListener) Close() error {
+	if sl.queue != nil {
+		sl.queue.Close()
+	}
+	return sl.db.Close()
+}
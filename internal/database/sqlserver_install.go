@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/sqlident"
+)
+
+const ms = sqlident.SQLServer
+
+// offsetsTable conserve, par instance de capture, la dernière position CDC
+// envoyée.
+const offsetsTable = "paypayo_offsets"
+
+// Longueur maximale d'un nom d'instance de capture CDC.
+const maxCaptureInstance = 100
+
+// sqlserverObjects active Change Data Capture sur la base et la table
+// surveillée. Les marqueurs de propriété sont stockés dans paypayo_objects,
+// dans le schéma de la table.
+type sqlserverObjects struct {
+	db     *sql.DB
+	table  sqlident.Name
+	config *config.Config
+	logger *logger.Logger
+}
+
+func sqlserverDSN(cfg *config.Config) string {
+	query := url.Values{}
+	query.Set("database", cfg.Database.Database)
+	query.Set("encrypt", cfg.Database.SSLMode)
+
+	u := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.Database.User, cfg.Database.Password),
+		Host:     fmt.Sprintf("%s:%d", cfg.Database.Host, cfg.Database.Port),
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+func openSQLServerObjects(cfg *config.Config, log *logger.Logger) (*sqlserverObjects, error) {
+	table, err := sqlident.ParseName(cfg.Database.Table)
+	if err == nil {
+		err = table.Validate(ms)
+	}
+	if err != nil {
+		return nil, i18n.Errorf("nom de table invalide: %w", err)
+	}
+	if table.Schema == "" {
+		table.Schema = cfg.Database.Schema
+	}
+	if table.Schema == "" {
+		table.Schema = "dbo"
+	}
+
+	db, err := sql.Open("sqlserver", sqlserverDSN(cfg))
+	if err != nil {
+		return nil, i18n.Errorf("erreur connexion SQL Server: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, i18n.Errorf("erreur ping SQL Server: %w", err)
+	}
+
+	return &sqlserverObjects{db: db, table: table, config: cfg, logger: log.With(logger.FieldTable, table.String())}, nil
+}
+
+// captureInstance nomme l'instance de capture de la table, raccourcie comme
+// les identifiants dérivés des autres bases si elle dépasse la limite CDC.
+func (o *sqlserverObjects) captureInstance() string {
+	name := "paypayo_" + o.table.Schema + "_" + o.table.Name
+	if len(name) <= maxCaptureInstance {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	return name[:maxCaptureInstance-9] + "_" + hex.EncodeToString(sum[:4])
+}
+
+func (o *sqlserverObjects) registry() string {
+	return ms.QuoteName(sqlident.Name{Schema: o.table.Schema, Name: registryTable})
+}
+
+func (o *sqlserverObjects) offsets() string {
+	return ms.QuoteName(sqlident.Name{Schema: o.table.Schema, Name: offsetsTable})
+}
+
+func (o *sqlserverObjects) plan(ctx context.Context) ([]dbObject, error) {
+	columns, err := o.columns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	instance := o.captureInstance()
+	params := fmt.Sprintf("@source_schema = %s, @source_name = %s, @capture_instance = %s",
+		ms.Literal(o.table.Schema), ms.Literal(o.table.Name), ms.Literal(instance))
+
+	return []dbObject{
+		{
+			// Activer CDC sur la base demande le rôle sysadmin; une base
+			// déjà activée par un DBA est utilisée telle quelle.
+			kind:    "CDC",
+			name:    o.config.Database.Database,
+			create:  []string{"EXEC sys.sp_cdc_enable_db"},
+			drop:    "EXEC sys.sp_cdc_disable_db",
+			enabled: true,
+			shared:  true,
+		},
+		{
+			kind: "TABLE",
+			name: sqlident.Name{Schema: o.table.Schema, Name: offsetsTable}.String(),
+			create: []string{fmt.Sprintf(`
+		CREATE TABLE %s (
+			capture_instance NVARCHAR(100) NOT NULL PRIMARY KEY,
+			last_lsn BINARY(10) NOT NULL,
+			last_seqval BINARY(10) NOT NULL,
+			updated_at DATETIME2 DEFAULT SYSUTCDATETIME()
+		)`, o.offsets())},
+			drop:     "DROP TABLE " + o.offsets(),
+			enabled:  true,
+			preserve: true,
+		},
+		{
+			// Les colonnes capturées sont figées à la création: une colonne
+			// ajoutée rend l'instance périmée, à recréer avec uninstall puis
+			// install une fois les changements en attente envoyés.
+			kind: "CAPTURE",
+			name: instance,
+			create: []string{fmt.Sprintf(
+				"EXEC sys.sp_cdc_enable_table %s, @role_name = NULL, @supports_net_changes = 0, @captured_column_list = %s",
+				params, ms.Literal(ms.QuoteList(names)))},
+			drop:     "EXEC sys.sp_cdc_disable_table " + params,
+			enabled:  true,
+			preserve: true,
+		},
+	}, nil
+}
+
+// columns lit les colonnes de la table: type de base (dataType) et type
+// déclaré, éventuellement défini par l'utilisateur (columnType).
+func (o *sqlserverObjects) columns(ctx context.Context) ([]column, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT c.name, TYPE_NAME(c.user_type_id), TYPE_NAME(c.system_type_id)
+		FROM sys.columns c
+		WHERE c.object_id = OBJECT_ID(@p1)
+		ORDER BY c.column_id
+	`, ms.QuoteName(o.table))
+	if err != nil {
+		return nil, i18n.Errorf("erreur récupération colonnes: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.columnType, &c.dataType); err != nil {
+			return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, i18n.Errorf("erreur lecture colonnes: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, i18n.Errorf("aucune colonne trouvée pour la table %s", o.table)
+	}
+
+	return columns, nil
+}
+
+// jsonSelect renvoie la sous-requête FOR JSON qui sérialise une ligne. Les
+// binaires sont passés en hexadécimal préfixé de \x, comme PostgreSQL, plutôt
+// qu'en base64 comme le ferait FOR JSON.
+func jsonSelect(columns []column, alias string) string {
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		ref := alias + "." + ms.Quote(c.name)
+		if sqlserverType(c.dataType) == typeBinary {
+			ref = fmt.Sprintf(`'\x' + CONVERT(VARCHAR(MAX), %s, 2)`, ref)
+		}
+		fields[i] = fmt.Sprintf("%s AS %s", ref, ms.Quote(c.name))
+	}
+	return fmt.Sprintf("(SELECT %s FOR JSON PATH, WITHOUT_ARRAY_WRAPPER, INCLUDE_NULL_VALUES)", strings.Join(fields, ", "))
+}
+
+func (o *sqlserverObjects) state(ctx context.Context, obj dbObject) (objectState, error) {
+	var query string
+	var args []interface{}
+
+	switch obj.kind {
+	case "CDC":
+		query = "SELECT COUNT(*) FROM sys.databases WHERE database_id = DB_ID() AND is_cdc_enabled = 1"
+	case "CAPTURE":
+		query = `SELECT COUNT(*) FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id WHERE s.name = 'cdc' AND t.name = @p1`
+		args = []interface{}{obj.name + "_CT"}
+	default:
+		query = "SELECT COUNT(*) FROM sys.tables WHERE object_id = OBJECT_ID(@p1)"
+		args = []interface{}{o.offsets()}
+	}
+
+	var count int
+	if err := o.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return stateMissing, nil
+	}
+
+	registered, err := o.registryExists(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !registered {
+		return stateForeign, nil
+	}
+
+	var marker string
+	err = o.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT marker FROM %s WHERE object_type = @p1 AND object_name = @p2", o.registry()),
+		obj.kind, obj.name).Scan(&marker)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return stateForeign, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return markerState(true, marker, obj.marker()), nil
+}
+
+func (o *sqlserverObjects) registryExists(ctx context.Context) (bool, error) {
+	var id sql.NullInt64
+	err := o.db.QueryRowContext(ctx, "SELECT OBJECT_ID(@p1, N'U')", o.registry()).Scan(&id)
+	return id.Valid, err
+}
+
+func (o *sqlserverObjects) registryDDL() string {
+	return fmt.Sprintf(`
+		IF OBJECT_ID(%s, N'U') IS NULL
+		CREATE TABLE %s (
+			object_type NVARCHAR(16) NOT NULL,
+			object_name NVARCHAR(255) NOT NULL,
+			marker NVARCHAR(64) NOT NULL,
+			installed_at DATETIME2 DEFAULT SYSUTCDATETIME(),
+			PRIMARY KEY (object_type, object_name)
+		)`, ms.Literal(o.registry()), o.registry())
+}
+
+func (o *sqlserverObjects) script(obj dbObject) []string {
+	where := fmt.Sprintf("object_type = %s AND object_name = %s", ms.Literal(obj.kind), ms.Literal(obj.name))
+	return append(append([]string{}, obj.create...),
+		fmt.Sprintf("DELETE FROM %s WHERE %s", o.registry(), where),
+		fmt.Sprintf("INSERT INTO %s (object_type, object_name, marker) VALUES (%s, %s, %s)",
+			o.registry(), ms.Literal(obj.kind), ms.Literal(obj.name), ms.Literal(obj.marker())))
+}
+
+func (o *sqlserverObjects) apply(ctx context.Context, obj dbObject, replace bool) error {
+	if _, err := o.db.ExecContext(ctx, o.registryDDL()); err != nil {
+		return i18n.Errorf("erreur création registre %s: %w", registryTable, err)
+	}
+
+	if replace && !obj.preserve {
+		if _, err := o.db.ExecContext(ctx, obj.drop); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range o.script(obj) {
+		if _, err := o.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *sqlserverObjects) remove(ctx context.Context, obj dbObject) error {
+	if _, err := o.db.ExecContext(ctx, obj.drop); err != nil {
+		return err
+	}
+
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE object_type = @p1 AND object_name = @p2", o.registry()),
+		obj.kind, obj.name)
+	return err
+}
+
+func (o *sqlserverObjects) Install(ctx context.Context, adopt bool) error {
+	return installObjects(ctx, o, o.logger, adopt)
+}
+
+// Uninstall supprime aussi le registre une fois vide.
+func (o *sqlserverObjects) Uninstall(ctx context.Context) error {
+	if err := uninstallObjects(ctx, o, o.logger); err != nil {
+		return err
+	}
+
+	registered, err := o.registryExists(ctx)
+	if err != nil || !registered {
+		return err
+	}
+
+	var remaining int
+	if err := o.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+o.registry()).Scan(&remaining); err != nil {
+		return err
+	}
+	if remaining == 0 {
+		if _, err := o.db.ExecContext(ctx, "DROP TABLE "+o.registry()); err != nil {
+			return err
+		}
+		o.logger.Info("Registre %s supprimé", registryTable)
+	}
+	return nil
+}
+
+func (o *sqlserverObjects) Verify(ctx context.Context) error {
+	return verifyObjects(ctx, o, o.logger)
+}
+
+func (o *sqlserverObjects) GenerateSQL(ctx context.Context, w io.Writer) error {
+	header := i18n.Sprintf("Objets paypayo pour la table %s (capture CDC %s)", o.table, o.captureInstance())
+	return writeScript(ctx, w, o, header, []string{o.registryDDL()})
+}
+
+func (o *sqlserverObjects) Close() error {
+	return o.db.Close()
+}
+
+// formatLSN affiche un LSN SQL Server comme Debezium: 0000002a:000001f0:0003.
+func formatLSN(lsn []byte) string {
+	if len(lsn) != 10 {
+		return hex.EncodeToString(lsn)
+	}
+	return hex.EncodeToString(lsn[:4]) + ":" + hex.EncodeToString(lsn[4:8]) + ":" + hex.EncodeToString(lsn[8:])
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/notifier"
+)
+
+// Snapshot envoie le contenu actuel de la table sous forme d'événements
+// SNAPSHOT, en reprenant au dernier checkpoint s'il existe.
+func (sl *SQLServerListener) Snapshot(ctx context.Context) error {
+	return runSnapshot(ctx, &sqlserverChunkReader{sl: sl}, eventSource(sl.config, sl.table), sl.types, sl.config, sl.logger, sl.gate, sl.workers)
+}
+
+// sqlserverChunkReader ne peut pas écrire de watermark dans le flux CDC: la
+// fenêtre de conflit est bornée par le LSN maximal capturé avant la lecture
+// et celui atteint une fois que la capture a dépassé l'heure de la lecture.
+type sqlserverChunkReader struct {
+	sl *SQLServerListener
+}
+
+func (r *sqlserverChunkReader) primaryKey(ctx context.Context) ([]string, error) {
+	rows, err := r.sl.db.QueryContext(ctx, `
+		SELECT c.name
+		FROM sys.indexes i
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1) AND i.is_primary_key = 1
+		ORDER BY ic.key_ordinal
+	`, ms.QuoteName(r.sl.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pk []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		pk = append(pk, col)
+	}
+	return pk, rows.Err()
+}
+
+func (r *sqlserverChunkReader) readChunk(ctx context.Context, pk []string, after []interface{}, limit int) ([]map[string]interface{}, []interface{}, error) {
+	var low []byte
+	if err := r.sl.db.QueryRowContext(ctx, "SELECT sys.fn_cdc_get_max_lsn()").Scan(&low); err != nil {
+		return nil, nil, i18n.Errorf("erreur lecture du LSN CDC: %w", err)
+	}
+
+	window := newChunkWindow(pk)
+	var lastKey []interface{}
+
+	query, args := r.chunkQuery(pk, after, limit)
+	rows, err := r.sl.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		row, err := decodeRow([]byte(raw))
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		window.add(row)
+		lastKey = keyValues(row, pk)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	high, err := r.waitCapture(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := r.applyWindow(ctx, low, high, window); err != nil {
+		return nil, nil, err
+	}
+
+	return window.result(), lastKey, nil
+}
+
+// waitCapture attend que la capture CDC ait traité le journal jusqu'à l'heure
+// de la lecture et renvoie le LSN maximal atteint.
+func (r *sqlserverChunkReader) waitCapture(ctx context.Context) ([]byte, error) {
+	var readAt time.Time
+	if err := r.sl.db.QueryRowContext(ctx, "SELECT SYSDATETIME()").Scan(&readAt); err != nil {
+		return nil, err
+	}
+
+	timeout := time.After(watermarkTimeout)
+	for {
+		var high []byte
+		var capturedAt sql.NullTime
+		err := r.sl.db.QueryRowContext(ctx,
+			"SELECT sys.fn_cdc_get_max_lsn(), sys.fn_cdc_map_lsn_to_time(sys.fn_cdc_get_max_lsn())").Scan(&high, &capturedAt)
+		if err != nil {
+			return nil, i18n.Errorf("erreur lecture du LSN CDC: %w", err)
+		}
+		if capturedAt.Valid && !capturedAt.Time.Before(readAt) {
+			return high, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, i18n.Errorf("capture CDC en retard de plus de %s (Agent SQL Server démarré ?)", watermarkTimeout)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func (r *sqlserverChunkReader) applyWindow(ctx context.Context, low, high []byte, window *chunkWindow) error {
+	if low == nil || bytes.Compare(low, high) >= 0 {
+		return nil
+	}
+
+	// Les changements antérieurs à la rétention ne sont plus lisibles, ni
+	// utiles: la lecture de la tranche est plus récente.
+	rows, err := r.sl.db.QueryContext(ctx, fmt.Sprintf(`
+		DECLARE @from BINARY(10) = sys.fn_cdc_increment_lsn(@p1);
+		DECLARE @min BINARY(10) = sys.fn_cdc_get_min_lsn(@p3);
+		IF @from < @min SET @from = @min;
+		SELECT %s FROM cdc.%s(@from, @p2, N'all update old') c
+	`, jsonSelect(r.sl.captured, "c"), ms.Quote("fn_cdc_get_all_changes_"+r.sl.captureInstance())),
+		low, high, r.sl.captureInstance())
+	if err != nil {
+		return i18n.Errorf("erreur lecture fenêtre CDC: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		var event notifier.ChangeEvent
+		event.Data, _ = decodeRow([]byte(data))
+		window.conflict(&event)
+	}
+	return rows.Err()
+}
+
+// chunkQuery parcourt la clé primaire sans comparaison de tuples, que SQL
+// Server ne supporte pas: (a > ?) OR (a = ? AND b > ?)...
+func (r *sqlserverChunkReader) chunkQuery(pk []string, after []interface{}, limit int) (string, []interface{}) {
+	selectSQL := fmt.Sprintf("SELECT TOP (%d) %s FROM %s t", limit, jsonSelect(r.sl.captured, "t"), ms.QuoteName(r.sl.table))
+	order := " ORDER BY " + ms.QuoteList(pk)
+
+	if after == nil {
+		return selectSQL + order, nil
+	}
+
+	var args []interface{}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("@p%d", len(args))
+	}
+
+	var or []string
+	for i := range pk {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, fmt.Sprintf("t.%s = %s", ms.Quote(pk[j]), param(after[j])))
+		}
+		and = append(and, fmt.Sprintf("t.%s > %s", ms.Quote(pk[i]), param(after[i])))
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return selectSQL + " WHERE " + strings.Join(or, " OR ") + order, args
+}
+
+func (r *sqlserverChunkReader) close() error {
+	return nil
+}
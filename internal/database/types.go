@@ -0,0 +1,270 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-db-listener/internal/notifier"
+)
+
+// Types logiques des colonnes, communs à toutes les bases.
+const (
+	typeString    = "string"
+	typeInteger   = "integer"
+	typeDecimal   = "decimal"
+	typeFloat     = "float"
+	typeBoolean   = "boolean"
+	typeBinary    = "binary"
+	typeTimestamp = "timestamp"
+	typeDate      = "date"
+	typeTime      = "time"
+	typeJSON      = "json"
+	typeArray     = "array"
+)
+
+// Formats des dates sans fuseau produits par row_to_json et JSON_OBJECT; ces
+// valeurs sont interprétées en UTC.
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// tableTypes connaît le type de chaque colonne et convertit les valeurs
+// décodées du JSON des triggers: décimaux en chaînes exactes, binaires en
+// base64, dates en RFC3339 avec fuseau.
+type tableTypes struct {
+	fields []notifier.Field
+	byName map[string]notifier.Field
+}
+
+func newTableTypes(fields []notifier.Field) *tableTypes {
+	byName := make(map[string]notifier.Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	return &tableTypes{fields: fields, byName: byName}
+}
+
+// apply convertit data et old_data et joint le schéma à l'événement.
+func (t *tableTypes) apply(event *notifier.ChangeEvent) {
+	if t == nil {
+		return
+	}
+	event.Data = t.encodeRow(event.Data)
+	event.OldData = t.encodeRow(event.OldData)
+	event.Schema = t.fields
+}
+
+func (t *tableTypes) encodeRow(row map[string]interface{}) map[string]interface{} {
+	if row == nil {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(row))
+	for name, v := range row {
+		// Une colonne inconnue (ajoutée depuis le chargement des types) garde
+		// sa valeur décodée; les nombres restent exacts grâce à json.Number.
+		if f, ok := t.byName[name]; ok {
+			v = encodeValue(f.Type, f.Items, v)
+		}
+		out[name] = v
+	}
+	return out
+}
+
+func encodeValue(typ, items string, v interface{}) interface{} {
+	switch typ {
+	case typeDecimal:
+		switch x := v.(type) {
+		case json.Number:
+			return x.String()
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		}
+	case typeInteger:
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return i
+			}
+			// BIGINT UNSIGNED au-delà de int64
+			return n.String()
+		}
+	case typeFloat:
+		if n, ok := v.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return f
+			}
+		}
+	case typeBoolean:
+		if n, ok := v.(json.Number); ok {
+			return n.String() != "0"
+		}
+	case typeBinary:
+		if s, ok := v.(string); ok {
+			return encodeBinary(s)
+		}
+	case typeTimestamp:
+		if s, ok := v.(string); ok {
+			return formatTimestamp(s)
+		}
+	case typeArray:
+		if list, ok := v.([]interface{}); ok {
+			out := make([]interface{}, len(list))
+			for i, e := range list {
+				out[i] = encodeValue(items, "", e)
+			}
+			return out
+		}
+	}
+	return v
+}
+
+// encodeBinary convertit en base64 les formats binaires des triggers:
+// "\x<hex>" pour PostgreSQL, "base64:typeNN:<base64>" pour MySQL.
+func encodeBinary(s string) string {
+	if strings.HasPrefix(s, `\x`) {
+		if raw, err := hex.DecodeString(s[2:]); err == nil {
+			return base64.StdEncoding.EncodeToString(raw)
+		}
+	}
+	if strings.HasPrefix(s, "base64:") {
+		if parts := strings.SplitN(s, ":", 3); len(parts) == 3 {
+			return parts[2]
+		}
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func formatTimestamp(s string) string {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.Format(time.RFC3339Nano)
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	// infinity, -infinity...
+	return s
+}
+
+// pgType associe un nom de type PostgreSQL (pg_type.typname) à un type
+// logique. Les tableaux ont un typname préfixé par "_".
+func pgType(typname string) (typ, items string) {
+	if elem, ok := strings.CutPrefix(typname, "_"); ok {
+		items, _ = pgType(elem)
+		return typeArray, items
+	}
+
+	switch typname {
+	case "int2", "int4", "int8", "oid":
+		return typeInteger, ""
+	case "numeric":
+		return typeDecimal, ""
+	case "float4", "float8":
+		return typeFloat, ""
+	case "bool":
+		return typeBoolean, ""
+	case "bytea":
+		return typeBinary, ""
+	case "timestamp", "timestamptz":
+		return typeTimestamp, ""
+	case "date":
+		return typeDate, ""
+	case "time", "timetz":
+		return typeTime, ""
+	case "json", "jsonb":
+		return typeJSON, ""
+	default:
+		return typeString, ""
+	}
+}
+
+// mysqlType associe DATA_TYPE (et COLUMN_TYPE pour tinyint(1)) à un type
+// logique.
+func mysqlType(dataType, columnType string) string {
+	switch strings.ToLower(dataType) {
+	case "tinyint":
+		if strings.HasPrefix(strings.ToLower(columnType), "tinyint(1)") {
+			return typeBoolean
+		}
+		return typeInteger
+	case "smallint", "mediumint", "int", "integer", "bigint", "year":
+		return typeInteger
+	case "decimal", "numeric":
+		return typeDecimal
+	case "float", "double", "real":
+		return typeFloat
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit":
+		return typeBinary
+	case "datetime", "timestamp":
+		return typeTimestamp
+	case "date":
+		return typeDate
+	case "time":
+		return typeTime
+	case "json":
+		return typeJSON
+	default:
+		return typeString
+	}
+}
+
+// sqliteType applique au type déclaré les règles d'affinité de SQLite, en
+// reconnaissant en plus les noms usuels (BOOLEAN, DATETIME, DECIMAL, JSON).
+func sqliteType(declared string) string {
+	t := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(t, "BOOL"):
+		return typeBoolean
+	case strings.Contains(t, "INT"):
+		return typeInteger
+	case strings.Contains(t, "JSON"):
+		return typeJSON
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return typeString
+	case strings.Contains(t, "BLOB"):
+		return typeBinary
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return typeFloat
+	case strings.Contains(t, "DEC"), strings.Contains(t, "NUMERIC"):
+		return typeDecimal
+	case strings.Contains(t, "DATETIME"), strings.Contains(t, "TIMESTAMP"):
+		return typeTimestamp
+	case t == "DATE":
+		return typeDate
+	case t == "TIME":
+		return typeTime
+	default:
+		return typeString
+	}
+}
+
+// sqlserverType associe un type système SQL Server (sys.types) à un type
+// logique.
+func sqlserverType(name string) string {
+	switch strings.ToLower(name) {
+	case "tinyint", "smallint", "int", "bigint":
+		return typeInteger
+	case "decimal", "numeric", "money", "smallmoney":
+		return typeDecimal
+	case "float", "real":
+		return typeFloat
+	case "bit":
+		return typeBoolean
+	case "binary", "varbinary", "image", "timestamp", "rowversion":
+		return typeBinary
+	case "datetime", "datetime2", "smalldatetime", "datetimeoffset":
+		return typeTimestamp
+	case "date":
+		return typeDate
+	case "time":
+		return typeTime
+	default:
+		return typeString
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/queue"
+)
+
+// workerPool consomme la file d'événements. Sa taille peut être modifiée à
+// chaud; un worker retiré termine l'envoi en cours avant de s'arrêter.
+type workerPool struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancels  []context.CancelFunc
+	queue    *queue.Queue
+	notifier *notifier.Notifier
+	logger   *logger.Logger
+
+	// Lignes de la tranche de snapshot en cours d'envoi.
+	snapMu      sync.Mutex
+	snapPending int
+	snapErr     error
+	snapSignal  chan struct{}
+}
+
+func newWorkerPool(q *queue.Queue, ntf *notifier.Notifier, log *logger.Logger) *workerPool {
+	return &workerPool{
+		queue:      q,
+		notifier:   ntf,
+		logger:     log,
+		snapSignal: make(chan struct{}, 1),
+	}
+}
+
+// start démarre les workers; sans effet s'ils tournent déjà (Listen et
+// snapshot_on_start).
+func (p *workerPool) start(ctx context.Context, size int) {
+	p.mu.Lock()
+	if p.ctx != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.ctx = ctx
+	p.mu.Unlock()
+
+	p.resize(size)
+}
+
+func (p *workerPool) resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ctx == nil {
+		return
+	}
+
+	for len(p.cancels) < size {
+		ctx, cancel := context.WithCancel(p.ctx)
+		id := len(p.cancels)
+		p.cancels = append(p.cancels, cancel)
+		go p.worker(ctx, id)
+	}
+
+	for len(p.cancels) > size {
+		last := len(p.cancels) - 1
+		p.cancels[last]()
+		p.cancels = p.cancels[:last]
+	}
+}
+
+func (p *workerPool) worker(ctx context.Context, id int) {
+	log := p.logger.With(logger.FieldWorkerID, id)
+	log.Debug("Worker %d démarré", id)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Worker %d arrêté", id)
+			return
+		case event := <-p.queue.C():
+			start := time.Now()
+			err := p.notifier.Notify(event, log)
+			if err != nil {
+				log.With(logger.FieldTable, event.Table, logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID,
+					logger.FieldDuration, time.Since(start).Milliseconds()).Error("Worker %d: Erreur notification: %v", id, err)
+			}
+			if event.Operation == notifier.OpSnapshot {
+				p.snapshotDone(err)
+			}
+		}
+	}
+}
+
+func (p *workerPool) expectSnapshot() {
+	p.snapMu.Lock()
+	defer p.snapMu.Unlock()
+
+	p.snapPending++
+}
+
+// snapshotDone compte une ligne envoyée. Les lignes SNAPSHOT restées sur
+// disque d'une exécution précédente ne sont pas attendues.
+func (p *workerPool) snapshotDone(err error) {
+	p.snapMu.Lock()
+	defer p.snapMu.Unlock()
+
+	if p.snapPending == 0 {
+		return
+	}
+	p.snapPending--
+	if err != nil && p.snapErr == nil {
+		p.snapErr = err
+	}
+	if p.snapPending == 0 {
+		select {
+		case p.snapSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// awaitSnapshot attend l'envoi des lignes mises en file et renvoie la
+// première erreur de notification.
+func (p *workerPool) awaitSnapshot(ctx context.Context) error {
+	for {
+		p.snapMu.Lock()
+		if p.snapPending == 0 {
+			err := p.snapErr
+			p.snapErr = nil
+			p.snapMu.Unlock()
+			return err
+		}
+		p.snapMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.snapSignal:
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package feed expose le flux d'événements aux tableaux de bord: Server-Sent
+// Events et WebSocket sur /events.
+package feed
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/stream"
+)
+
+// Intervalle des messages de maintien de connexion (commentaire SSE, ping
+// WebSocket).
+const heartbeat = 15 * time.Second
+
+// message est un événement tel qu'envoyé aux clients, avec son offset.
+type message struct {
+	Offset uint64 `json:"offset"`
+	*notifier.ChangeEvent
+}
+
+type handler struct {
+	hub      *stream.Hub
+	config   *config.HTTPConfig
+	logger   *logger.Logger
+	upgrader websocket.Upgrader
+}
+
+// Serve démarre le serveur HTTP du flux en arrière-plan.
+func Serve(cfg *config.HTTPConfig, hub *stream.Hub, log *logger.Logger) (*http.Server, error) {
+	h := &handler{hub: hub, config: cfg, logger: log}
+	h.upgrader.CheckOrigin = h.checkOrigin
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", h)
+
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, i18n.Errorf("erreur écoute HTTP: %w", err)
+	}
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		var err error
+		if cfg.TLSCert != "" {
+			err = srv.ServeTLS(lis, cfg.TLSCert, cfg.TLSKey)
+		} else {
+			err = srv.Serve(lis)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Erreur serveur du flux HTTP: %v", err)
+		}
+	}()
+
+	log.Info("Flux SSE/WebSocket exposé sur %s/events", cfg.Addr)
+	return srv, nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && h.allowed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Last-Event-ID")
+		w.Header().Set("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, i18n.T("méthode non autorisée"), http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorized(r) {
+		http.Error(w, i18n.T("jeton absent ou invalide"), http.StatusUnauthorized)
+		return
+	}
+
+	filter, start, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.hub.Subscribe(filter, start)
+	switch {
+	case errors.Is(err, stream.ErrUnknownPosition):
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r, sub)
+	} else {
+		h.serveSSE(w, r, sub)
+	}
+}
+
+// authorized vérifie le jeton, passé en "Authorization: Bearer" ou, pour
+// EventSource qui ne peut pas poser d'en-tête, en paramètre token.
+func (h *handler) authorized(r *http.Request) bool {
+	if h.config.Token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.config.Token)) == 1
+}
+
+func (h *handler) allowed(origin string) bool {
+	return slices.Contains(h.config.AllowedOrigins, "*") || slices.Contains(h.config.AllowedOrigins, origin)
+}
+
+// checkOrigin accepte les clients hors navigateur, la même origine et les
+// origines de http.allowed_origins.
+func (h *handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.allowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// parseRequest lit les filtres et le point de reprise:
+//
+//	table=public.users&operation=UPDATE&data.status=refunded&last_event_id=42
+//
+// table et operation acceptent plusieurs valeurs (répétées ou séparées par
+// des virgules).
+func parseRequest(r *http.Request) (stream.Filter, stream.Start, error) {
+	q := r.URL.Query()
+
+	filter := stream.NewFilter(splitValues(q["table"]), splitValues(q["operation"]))
+	for key, values := range q {
+		if column, ok := strings.CutPrefix(key, "data."); ok && column != "" {
+			filter.WhereColumn(column, values...)
+		}
+	}
+
+	var start stream.Start
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = q.Get("last_event_id")
+	}
+	if last != "" {
+		offset, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return filter, start, i18n.Errorf("last_event_id %q invalide: offset attendu", last)
+		}
+		start.Offset = &offset
+	} else if id := q.Get("after_id"); id != "" {
+		start.ID = id
+	}
+	return filter, start, nil
+}
+
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+func (h *handler) serveSSE(w http.ResponseWriter, r *http.Request, sub *stream.Subscription) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, i18n.T("streaming non supporté"), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	h.logger.Info("Client SSE connecté: %s", r.RemoteAddr)
+	defer h.logger.Info("Client SSE déconnecté: %s", r.RemoteAddr)
+
+	for {
+		e, err := next(r.Context(), sub)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Fprint(w, ": ping\n\n")
+		case err != nil:
+			if r.Context().Err() == nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+				flusher.Flush()
+			}
+			return
+		default:
+			data, err := json.Marshal(message{Offset: e.Offset, ChangeEvent: e.Event})
+			if err != nil {
+				h.logger.Error("Erreur marshalling événement %d: %v", e.Offset, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Offset, data)
+		}
+		flusher.Flush()
+	}
+}
+
+func (h *handler) serveWebSocket(w http.ResponseWriter, r *http.Request, sub *stream.Subscription) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade a déjà répondu au client
+		return
+	}
+	defer conn.Close()
+
+	h.logger.Info("Client WebSocket connecté: %s", r.RemoteAddr)
+	defer h.logger.Info("Client WebSocket déconnecté: %s", r.RemoteAddr)
+
+	// Les messages du client sont ignorés; la lecture traite les pong et
+	// détecte la fermeture.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		e, err := next(ctx, sub)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat)); err != nil {
+				return
+			}
+		case err != nil:
+			if ctx.Err() == nil {
+				code := websocket.CloseGoingAway
+				if errors.Is(err, stream.ErrSlowConsumer) {
+					code = websocket.CloseTryAgainLater
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, err.Error()), time.Now().Add(time.Second))
+			}
+			return
+		default:
+			conn.SetWriteDeadline(time.Now().Add(heartbeat))
+			if err := conn.WriteJSON(message{Offset: e.Offset, ChangeEvent: e.Event}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// next attend l'événement suivant au plus heartbeat; DeadlineExceeded
+// indique qu'il faut entretenir la connexion.
+func next(ctx context.Context, sub *stream.Subscription) (stream.Entry, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, heartbeat)
+	defer cancel()
+
+	e, err := sub.Next(waitCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return e, ctx.Err()
+	}
+	return e, err
+}
@@ -0,0 +1,52 @@
+// Package grpcapi expose le flux d'événements par gRPC (service ChangeStream)
+// et pousse les événements vers un service ChangeSink.
+package grpcapi
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"app-db-listener/internal/grpcapi/paypayov1"
+	"app-db-listener/internal/notifier"
+)
+
+// toProto convertit un événement; data et old_data restent en JSON pour
+// garder les nombres exacts.
+func toProto(offset uint64, event *notifier.ChangeEvent) (*paypayov1.ChangeEvent, error) {
+	msg := &paypayov1.ChangeEvent{
+		Id:        event.ID,
+		Offset:    offset,
+		Operation: event.Operation,
+		Table:     event.Table,
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = string(data)
+
+	if event.OldData != nil {
+		old, err := json.Marshal(event.OldData)
+		if err != nil {
+			return nil, err
+		}
+		msg.OldData = string(old)
+	}
+
+	for _, f := range event.Schema {
+		msg.Schema = append(msg.Schema, &paypayov1.Field{Name: f.Name, Type: f.Type, Items: f.Items, DbType: f.DBType})
+	}
+	if s := event.Source; s != nil {
+		msg.Source = &paypayov1.Source{
+			Connector: s.Connector,
+			Db:        s.Database,
+			Schema:    s.Schema,
+			Table:     s.Table,
+			Position:  s.Position,
+		}
+	}
+	return msg, nil
+}
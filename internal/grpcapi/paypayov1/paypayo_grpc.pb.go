@@ -0,0 +1,226 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: proto/paypayo/v1/paypayo.proto
+
+package paypayov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ChangeStream_Subscribe_FullMethodName = "/paypayo.v1.ChangeStream/Subscribe"
+)
+
+// ChangeStreamClient is the client API for ChangeStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChangeStreamClient interface {
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+}
+
+type changeStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChangeStreamClient(cc grpc.ClientConnInterface) ChangeStreamClient {
+	return &changeStreamClient{cc}
+}
+
+func (c *changeStreamClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ChangeStream_ServiceDesc.Streams[0], ChangeStream_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ChangeStream_SubscribeClient = grpc.ServerStreamingClient[ChangeEvent]
+
+// ChangeStreamServer is the server API for ChangeStream service.
+// All implementations must embed UnimplementedChangeStreamServer
+// for forward compatibility.
+type ChangeStreamServer interface {
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error
+	mustEmbedUnimplementedChangeStreamServer()
+}
+
+// UnimplementedChangeStreamServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChangeStreamServer struct{}
+
+func (UnimplementedChangeStreamServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedChangeStreamServer) mustEmbedUnimplementedChangeStreamServer() {}
+func (UnimplementedChangeStreamServer) testEmbeddedByValue()                      {}
+
+// UnsafeChangeStreamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChangeStreamServer will
+// result in compilation errors.
+type UnsafeChangeStreamServer interface {
+	mustEmbedUnimplementedChangeStreamServer()
+}
+
+func RegisterChangeStreamServer(s grpc.ServiceRegistrar, srv ChangeStreamServer) {
+	// If the following call pancis, it indicates UnimplementedChangeStreamServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ChangeStream_ServiceDesc, srv)
+}
+
+func _ChangeStream_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChangeStreamServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ChangeStream_SubscribeServer = grpc.ServerStreamingServer[ChangeEvent]
+
+// ChangeStream_ServiceDesc is the grpc.ServiceDesc for ChangeStream service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ChangeStream_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "paypayo.v1.ChangeStream",
+	HandlerType: (*ChangeStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _ChangeStream_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/paypayo/v1/paypayo.proto",
+}
+
+const (
+	ChangeSink_Push_FullMethodName = "/paypayo.v1.ChangeSink/Push"
+)
+
+// ChangeSinkClient is the client API for ChangeSink service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChangeSinkClient interface {
+	Push(ctx context.Context, in *ChangeEvent, opts ...grpc.CallOption) (*PushResponse, error)
+}
+
+type changeSinkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChangeSinkClient(cc grpc.ClientConnInterface) ChangeSinkClient {
+	return &changeSinkClient{cc}
+}
+
+func (c *changeSinkClient) Push(ctx context.Context, in *ChangeEvent, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, ChangeSink_Push_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChangeSinkServer is the server API for ChangeSink service.
+// All implementations must embed UnimplementedChangeSinkServer
+// for forward compatibility.
+type ChangeSinkServer interface {
+	Push(context.Context, *ChangeEvent) (*PushResponse, error)
+	mustEmbedUnimplementedChangeSinkServer()
+}
+
+// UnimplementedChangeSinkServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChangeSinkServer struct{}
+
+func (UnimplementedChangeSinkServer) Push(context.Context, *ChangeEvent) (*PushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Push not implemented")
+}
+func (UnimplementedChangeSinkServer) mustEmbedUnimplementedChangeSinkServer() {}
+func (UnimplementedChangeSinkServer) testEmbeddedByValue()                    {}
+
+// UnsafeChangeSinkServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChangeSinkServer will
+// result in compilation errors.
+type UnsafeChangeSinkServer interface {
+	mustEmbedUnimplementedChangeSinkServer()
+}
+
+func RegisterChangeSinkServer(s grpc.ServiceRegistrar, srv ChangeSinkServer) {
+	// If the following call pancis, it indicates UnimplementedChangeSinkServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ChangeSink_ServiceDesc, srv)
+}
+
+func _ChangeSink_Push_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeEvent)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangeSinkServer).Push(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChangeSink_Push_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangeSinkServer).Push(ctx, req.(*ChangeEvent))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ChangeSink_ServiceDesc is the grpc.ServiceDesc for ChangeSink service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ChangeSink_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "paypayo.v1.ChangeSink",
+	HandlerType: (*ChangeSinkServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Push",
+			Handler:    _ChangeSink_Push_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/paypayo/v1/paypayo.proto",
+}
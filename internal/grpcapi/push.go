@@ -0,0 +1,88 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/grpcapi/paypayov1"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+)
+
+// Pusher appelle ChangeSink.Push pour chaque événement (notifier.Sink).
+type Pusher struct {
+	conn   *grpc.ClientConn
+	client paypayov1.ChangeSinkClient
+	config *config.GRPCConfig
+	logger *logger.Logger
+	// Fermé par Close: interrompt l'attente entre deux tentatives.
+	done chan struct{}
+}
+
+func NewPusher(cfg *config.GRPCConfig, log *logger.Logger) (*Pusher, error) {
+	creds := insecure.NewCredentials()
+	if cfg.PushTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	conn, err := grpc.NewClient(cfg.PushTarget, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, i18n.Errorf("erreur connexion gRPC %s: %w", cfg.PushTarget, err)
+	}
+
+	log.Info("Événements poussés vers le service gRPC %s", cfg.PushTarget)
+	return &Pusher{
+		conn:   conn,
+		client: paypayov1.NewChangeSinkClient(conn),
+		config: cfg,
+		logger: log,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+func (p *Pusher) Publish(event *notifier.ChangeEvent) error {
+	msg, err := toProto(0, event)
+	if err != nil {
+		return i18n.Errorf("erreur conversion événement: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= p.config.PushRetryCount; attempt++ {
+		if attempt > 0 {
+			p.logger.Info("Tentative %d/%d de push gRPC pour l'événement %s", attempt, p.config.PushRetryCount, event.Operation)
+			select {
+			case <-time.After(time.Duration(p.config.PushRetryDelay) * time.Second):
+			case <-p.done:
+				return i18n.Errorf("push gRPC: %w", lastErr)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.config.PushTimeout)*time.Second)
+		_, err := p.client.Push(ctx, msg)
+		cancel()
+		if err == nil {
+			metrics.EventsDelivered.Add(1)
+			return nil
+		}
+
+		lastErr = err
+		p.logger.Warn("Erreur push gRPC (tentative %d): %v", attempt+1, err)
+	}
+
+	p.logger.Error("Échec push gRPC après %d tentatives: %v", p.config.PushRetryCount+1, lastErr)
+	metrics.EventsFailed.Add(1)
+	return i18n.Errorf("push gRPC: %w", lastErr)
+}
+
+func (p *Pusher) Close() error {
+	close(p.done)
+	return p.conn.Close()
+}
@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/grpcapi/paypayov1"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/stream"
+)
+
+type server struct {
+	paypayov1.UnimplementedChangeStreamServer
+	hub    *stream.Hub
+	logger *logger.Logger
+}
+
+// Serve démarre le serveur gRPC ChangeStream en arrière-plan.
+func Serve(cfg *config.GRPCConfig, hub *stream.Hub, log *logger.Logger) (*grpc.Server, error) {
+	var opts []grpc.ServerOption
+	if cfg.TLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, i18n.Errorf("erreur chargement certificat gRPC: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, i18n.Errorf("erreur écoute gRPC: %w", err)
+	}
+
+	srv := grpc.NewServer(opts...)
+	paypayov1.RegisterChangeStreamServer(srv, &server{hub: hub, logger: log})
+
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Error("Erreur serveur gRPC: %v", err)
+		}
+	}()
+
+	log.Info("Flux gRPC exposé sur %s", cfg.Addr)
+	return srv, nil
+}
+
+func (s *server) Subscribe(req *paypayov1.SubscribeRequest, srv paypayov1.ChangeStream_SubscribeServer) error {
+	var start stream.Start
+	switch x := req.Start.(type) {
+	case *paypayov1.SubscribeRequest_AfterOffset:
+		start.Offset = &x.AfterOffset
+	case *paypayov1.SubscribeRequest_AfterId:
+		start.ID = x.AfterId
+	}
+
+	sub, err := s.hub.Subscribe(stream.NewFilter(req.Tables, req.Operations), start)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer sub.Close()
+
+	s.logger.Info("Abonné gRPC connecté (tables: %v, opérations: %v)", req.Tables, req.Operations)
+	defer s.logger.Info("Abonné gRPC déconnecté")
+
+	for {
+		e, err := sub.Next(srv.Context())
+		if err != nil {
+			return toStatus(err)
+		}
+		msg, err := toProto(e.Offset, e.Event)
+		if err != nil {
+			return status.Error(codes.Internal, i18n.Sprintf("erreur conversion événement %d: %v", e.Offset, err))
+		}
+		if err := srv.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, stream.ErrUnknownPosition):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, stream.ErrSlowConsumer):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, stream.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
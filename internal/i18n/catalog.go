@@ -0,0 +1,470 @@
+package i18n
+
+// catalog est indexé par le texte source français, tel qu'écrit dans le
+// code. Les codes des messages de log ne doivent pas changer: des règles
+// d'alerte en dépendent.
+var catalog = map[string]entry{
+	// Logs: démarrage, arrêt et rechargement
+	"=== Démarrage de l'application DB Listener ===":            {"APP_STARTING", "=== Starting DB Listener application ==="},
+	"=== Application arrêtée ===":                               {"APP_STOPPED", "=== Application stopped ==="},
+	"Type de base de données: %s":                               {"APP_DATABASE_TYPE", "Database type: %s"},
+	"Table surveillée: %s":                                      {"APP_TABLE", "Watched table: %s"},
+	"Modes activés: %s":                                         {"APP_MODES", "Enabled modes: %s"},
+	"URL webhook: %s":                                           {"APP_WEBHOOK_URL", "Webhook URL: %s"},
+	"Workers: %d":                                               {"APP_WORKERS", "Workers: %d"},
+	"Application démarrée et en écoute...":                      {"APP_STARTED", "Application started and listening..."},
+	"Signal d'arrêt reçu, fermeture de l'application...":        {"APP_SHUTDOWN", "Shutdown signal received, closing application..."},
+	"Métriques: %s":                                             {"APP_METRICS", "Metrics: %s"},
+	"Erreur initialisation notifier: %v":                        {"NOTIFIER_INIT_FAILED", "Notifier initialization error: %v"},
+	"Erreur initialisation des flux: %v":                        {"STREAM_INIT_FAILED", "Stream initialization error: %v"},
+	"Erreur initialisation listener: %v":                        {"LISTENER_INIT_FAILED", "Listener initialization error: %v"},
+	"Erreur du listener: %v":                                    {"LISTENER_FAILED", "Listener error: %v"},
+	"Erreur snapshot: %v":                                       {"SNAPSHOT_FAILED", "Snapshot error: %v"},
+	"Erreur installation: %v":                                   {"INSTALL_FAILED", "Installation error: %v"},
+	"Erreur désinstallation: %v":                                {"UNINSTALL_FAILED", "Uninstallation error: %v"},
+	"SIGUSR1 reçu, fichier de log rouvert":                      {"LOG_REOPENED", "SIGUSR1 received, log file reopened"},
+	"Erreur rotation du fichier de log: %v":                     {"LOG_ROTATE_FAILED", "Log file rotation error: %v"},
+	"SIGHUP reçu, rechargement de %s":                           {"RELOAD_STARTED", "SIGHUP received, reloading %s"},
+	"Rechargement refusé, configuration actuelle conservée: %v": {"RELOAD_REJECTED", "Reload rejected, keeping current configuration: %v"},
+	"Rechargement: aucun changement":                            {"RELOAD_UNCHANGED", "Reload: no changes"},
+	"Rechargement: changements appliqués: %s":                   {"RELOAD_APPLIED", "Reload: changes applied: %s"},
+	"Rechargement: changements non appliqués, ils nécessitent de réinstaller les triggers (redémarrage): %s": {"RELOAD_NEEDS_TRIGGERS",
+		"Reload: changes not applied, they require reinstalling the triggers (restart): %s"},
+	"Rechargement: changements pris en compte au prochain démarrage: %s": {"RELOAD_NEEDS_RESTART", "Reload: changes will apply on next start: %s"},
+
+	// Logs: haute disponibilité
+	"Instance active arrêtée: %v; retour en attente du verrou":             {"HA_ACTIVE_STOPPED", "Active instance stopped: %v; waiting for the lock again"},
+	"Erreur prise du verrou %s: %v":                                        {"HA_LOCK_FAILED", "Error acquiring lock %s: %v"},
+	"Verrou %s obtenu, instance active":                                    {"HA_LOCK_ACQUIRED", "Lock %s acquired, instance is active"},
+	"Verrou %s détenu par une autre instance, instance passive en attente": {"HA_LOCK_BUSY", "Lock %s held by another instance, passive instance waiting"},
+
+	// Logs: objets paypayo
+	"%s existe déjà, utilisé tel quel": {"OBJECT_ADOPTED", "%s already exists, used as is"},
+	"%s supprimé (mode désactivé)":     {"OBJECT_REMOVED_DISABLED", "%s removed (mode disabled)"},
+	"%s à jour":                        {"OBJECT_CURRENT", "%s is up to date"},
+	"%s créé":                          {"OBJECT_CREATED", "%s created"},
+	"%s a une structure différente de la version attendue; conservé tel quel": {"OBJECT_STRUCTURE_DIFFERS", "%s differs from the expected version; kept as is"},
+	"%s mis à jour": {"OBJECT_UPDATED", "%s updated"},
+	"%s n'a pas été créé par paypayo, conservé": {"OBJECT_FOREIGN_KEPT", "%s was not created by paypayo, kept"},
+	"%s conservé: %v": {"OBJECT_KEPT", "%s kept: %v"},
+	"%s supprimé":     {"OBJECT_REMOVED", "%s removed"},
+	"%s existe alors que le mode est désactivé; il peut être supprimé": {"OBJECT_UNUSED", "%s exists while its mode is disabled; it can be removed"},
+	"%s régénéré":              {"TRIGGER_REGENERATED", "%s regenerated"},
+	"Registre %s supprimé":     {"REGISTRY_REMOVED", "Registry %s removed"},
+	"Serveur détecté: %s (%s)": {"DB_SERVER_DETECTED", "Detected server: %s (%s)"},
+
+	// Logs: écoute et polling
+	"Écoute démarrée sur la table: %s (polling toutes les %d à %d secondes, lots de %d lignes)": {"LISTEN_STARTED",
+		"Listening on table: %s (polling every %d to %d seconds, batches of %d rows)"},
+	"Écoute démarrée sur la table: %s (capture CDC %s, polling toutes les %d à %d secondes, lots de %d changements)": {"LISTEN_STARTED",
+		"Listening on table: %s (CDC capture %s, polling every %d to %d seconds, batches of %d changes)"},
+	"Écoute démarrée sur le canal: %s":                                      {"LISTEN_STARTED", "Listening on channel: %s"},
+	"Arrêt de l'écoute":                                                     {"LISTEN_STOPPED", "Listening stopped"},
+	"Erreur polling: %v":                                                    {"POLL_FAILED", "Polling error: %v"},
+	"Erreur contrôle du schéma: %v":                                         {"SCHEMA_CHECK_FAILED", "Schema check error: %v"},
+	"Ligne d'audit %d illisible, marquée comme traitée: %v":                 {"AUDIT_ROW_INVALID", "Unreadable audit row %d marked as processed: %v"},
+	"Erreur unmarshal data: %v":                                             {"EVENT_DATA_INVALID", "Data unmarshal error: %v"},
+	"Erreur unmarshalling notification: %v":                                 {"EVENT_DATA_INVALID", "Notification unmarshal error: %v"},
+	"Erreur marquage processed: %v":                                         {"AUDIT_MARK_FAILED", "Error marking rows processed: %v"},
+	"Événement %d non mis en file (%s): %v":                                 {"QUEUE_REJECTED", "Event %d not queued (%s): %v"},
+	"Événement %s non mis en file (%s): %v":                                 {"QUEUE_REJECTED", "Event %s not queued (%s): %v"},
+	"Événement illisible dans le fichier de débordement, ignoré: %v":        {"SPILL_EVENT_LOST", "Unreadable event in spill file, skipped: %v"},
+	"Erreur lecture du fichier de débordement: %v":                          {"SPILL_READ_FAILED", "Spill file read error: %v"},
+	"Fichier de débordement: dernière ligne incomplète (%d octets) ignorée": {"SPILL_TRUNCATED", "Spill file: incomplete last line (%d bytes) skipped"},
+	"Changement %s non mis en file (%s): %v":                                {"QUEUE_REJECTED", "Change %s not queued (%s): %v"},
+	"Événement listener: %v":                                                {"PG_LISTENER_EVENT", "Listener event: %v"},
+	"Événement listener snapshot: %v":                                       {"PG_LISTENER_EVENT", "Snapshot listener event: %v"},
+	"Colonne %s non capturée par %s, absente des événements":                {"CDC_COLUMN_NOT_CAPTURED", "Column %s not captured by %s, missing from events"},
+	"Reprise de la capture %s après le LSN %s":                              {"CDC_RESUMED", "Resuming capture %s after LSN %s"},
+	"Erreur enregistrement de la position CDC: %v":                          {"CDC_OFFSET_SAVE_FAILED", "Error saving CDC position: %v"},
+	"Position %s sortie de la rétention CDC, reprise au LSN %s: des changements ont été perdus": {"CDC_RETENTION_LOST",
+		"Position %s is past CDC retention, resuming at LSN %s: changes were lost"},
+
+	// Logs: table d'audit et schéma
+	"Erreur nettoyage de la table d'audit: %v":                                                {"AUDIT_CLEANUP_FAILED", "Audit table cleanup error: %v"},
+	"Erreur lecture statistiques d'audit: %v":                                                 {"AUDIT_STATS_FAILED", "Error reading audit statistics: %v"},
+	"Table d'audit: %d lignes traitées antérieures au %s supprimées":                          {"AUDIT_PURGED", "Audit table: %d processed rows older than %s deleted"},
+	"Conversion de la table d'audit en partitions journalières, l'opération peut être longue": {"AUDIT_PARTITIONING", "Converting the audit table to daily partitions, this may take a while"},
+	"Table d'audit partitionnée par jour":                                                     {"AUDIT_PARTITIONED", "Audit table partitioned by day"},
+	"Partition d'audit %s créée":                                                              {"AUDIT_PARTITION_CREATED", "Audit partition %s created"},
+	"Partition d'audit %s conservée: %d lignes non traitées":                                  {"AUDIT_PARTITION_KEPT", "Audit partition %s kept: %d unprocessed rows"},
+	"Partition d'audit %s supprimée (%d lignes)":                                              {"AUDIT_PARTITION_DROPPED", "Audit partition %s dropped (%d rows)"},
+	"Schéma de la table %s modifié: ajoutées %v, supprimées %v, modifiées %v (maintenance assurée par une autre instance)": {"SCHEMA_CHANGED",
+		"Table %s schema changed: added %v, removed %v, modified %v (maintenance handled by another instance)"},
+	"Schéma de la table %s modifié: ajoutées %v, supprimées %v, modifiées %v": {"SCHEMA_CHANGED", "Table %s schema changed: added %v, removed %v, modified %v"},
+	"manage_triggers désactivé: triggers non régénérés, appliquez le SQL de 'paypayo generate-sql'": {"TRIGGERS_STALE",
+		"manage_triggers disabled: triggers not regenerated, apply the SQL from 'paypayo generate-sql'"},
+
+	// Logs: snapshot
+	"Reprise du snapshot de %s après la clé %v (%d lignes déjà envoyées)": {"SNAPSHOT_RESUMED", "Resuming snapshot of %s after key %v (%d rows already sent)"},
+	"Snapshot démarré sur la table %s (clé: %s, tranches de %d lignes)":   {"SNAPSHOT_STARTED", "Snapshot started on table %s (key: %s, chunks of %d rows)"},
+	"Tranche de snapshot envoyée: %d lignes, dernière clé %v":             {"SNAPSHOT_CHUNK_SENT", "Snapshot chunk sent: %d rows, last key %v"},
+	"Erreur suppression checkpoint %s: %v":                                {"SNAPSHOT_CHECKPOINT_REMOVE_FAILED", "Error removing checkpoint %s: %v"},
+	"Snapshot terminé sur la table %s: %d lignes envoyées":                {"SNAPSHOT_DONE", "Snapshot finished on table %s: %d rows sent"},
+
+	// Logs: envoi des notifications
+	"Worker %d démarré":                                     {"WORKER_STARTED", "Worker %d started"},
+	"Worker %d arrêté":                                      {"WORKER_STOPPED", "Worker %d stopped"},
+	"Worker %d: Erreur notification: %v":                    {"NOTIFY_FAILED", "Worker %d: notification error: %v"},
+	"Événement %s filtré%s":                                 {"EVENT_FILTERED", "Event %s filtered%s"},
+	"Événement %s sans équivalent au format %s, non envoyé": {"EVENT_UNSUPPORTED_FORMAT", "Event %s has no equivalent in format %s, not sent"},
+	"Tentative %d/%d pour l'événement %s":                   {"WEBHOOK_RETRY", "Attempt %d/%d for event %s"},
+	"Erreur création requête%s: %v":                         {"WEBHOOK_REQUEST_INVALID", "Request creation error%s: %v"},
+	"Erreur envoi webhook (tentative %d)%s: %v":             {"WEBHOOK_SEND_FAILED", "Webhook send error (attempt %d)%s: %v"},
+	"Notification envoyée avec succès: %s sur table %s%s":   {"WEBHOOK_DELIVERED", "Notification delivered: %s on table %s%s"},
+	"Webhook retourné statut %d (tentative %d)%s":           {"WEBHOOK_BAD_STATUS", "Webhook returned status %d (attempt %d)%s"},
+	"Échec notification après %d tentatives%s: %v":          {"WEBHOOK_GAVE_UP", "Notification failed after %d attempts%s: %v"},
+
+	// Logs: flux gRPC, HTTP et métriques
+	"Événements poussés vers le service gRPC %s":            {"GRPC_PUSH_STARTED", "Events pushed to gRPC service %s"},
+	"Tentative %d/%d de push gRPC pour l'événement %s":      {"GRPC_PUSH_RETRY", "gRPC push attempt %d/%d for event %s"},
+	"Erreur push gRPC (tentative %d): %v":                   {"GRPC_PUSH_FAILED", "gRPC push error (attempt %d): %v"},
+	"Échec push gRPC après %d tentatives: %v":               {"GRPC_PUSH_GAVE_UP", "gRPC push failed after %d attempts: %v"},
+	"Erreur serveur gRPC: %v":                               {"GRPC_SERVER_FAILED", "gRPC server error: %v"},
+	"Flux gRPC exposé sur %s":                               {"GRPC_SERVER_STARTED", "gRPC stream served on %s"},
+	"Abonné gRPC connecté (tables: %v, opérations: %v)":     {"GRPC_SUBSCRIBER_CONNECTED", "gRPC subscriber connected (tables: %v, operations: %v)"},
+	"Abonné gRPC déconnecté":                                {"GRPC_SUBSCRIBER_DISCONNECTED", "gRPC subscriber disconnected"},
+	"Erreur serveur du flux HTTP: %v":                       {"HTTP_SERVER_FAILED", "HTTP stream server error: %v"},
+	"Flux SSE/WebSocket exposé sur %s/events":               {"HTTP_SERVER_STARTED", "SSE/WebSocket stream served on %s/events"},
+	"Client SSE connecté: %s":                               {"SSE_CLIENT_CONNECTED", "SSE client connected: %s"},
+	"Client SSE déconnecté: %s":                             {"SSE_CLIENT_DISCONNECTED", "SSE client disconnected: %s"},
+	"Client WebSocket connecté: %s":                         {"WS_CLIENT_CONNECTED", "WebSocket client connected: %s"},
+	"Client WebSocket déconnecté: %s":                       {"WS_CLIENT_DISCONNECTED", "WebSocket client disconnected: %s"},
+	"Erreur marshalling événement %d: %v":                   {"STREAM_EVENT_INVALID", "Error marshalling event %d: %v"},
+	"Journal %s relu, reprise du flux à l'offset %d":        {"STREAM_JOURNAL_LOADED", "Journal %s loaded, stream resumes at offset %d"},
+	"Abonné au flux déconnecté: tampon plein à l'offset %d": {"STREAM_SLOW_CONSUMER", "Stream subscriber disconnected: buffer full at offset %d"},
+	"Erreur serveur métriques: %v":                          {"METRICS_SERVER_FAILED", "Metrics server error: %v"},
+	"Métriques exposées sur http://%s/metrics":              {"METRICS_SERVER_STARTED", "Metrics served on http://%s/metrics"},
+	"reçus=%d en_file=%d perdus=%d sur_disque=%d bloqués=%d livrés=%d échecs=%d": {"",
+		"received=%d queued=%d dropped=%d spilled=%d blocked=%d delivered=%d failed=%d"},
+
+	// Console
+	`Usage: paypayo [-config config.yaml] [commande]
+
+Commandes:
+  run        écoute la table et envoie les notifications (par défaut)
+  snapshot   envoie le contenu actuel de la table (événements SNAPSHOT)
+  install [-adopt]
+             crée ou met à jour les objets paypayo (triggers, fonctions, tables d'audit)
+  uninstall  supprime les objets créés par paypayo, et seulement ceux-là
+  generate-sql [-o fichier]
+             affiche le SQL d'installation à faire appliquer par un DBA
+  config validate [fichier]
+             vérifie la configuration sans se connecter à la base
+
+Options:
+`: {"", `Usage: paypayo [-config config.yaml] [command]
+
+Commands:
+  run        watch the table and send notifications (default)
+  snapshot   send the current content of the table (SNAPSHOT events)
+  install [-adopt]
+             create or update the paypayo objects (triggers, functions, audit tables)
+  uninstall  remove the objects created by paypayo, and only those
+  generate-sql [-o file]
+             print the installation SQL for a DBA to apply
+  config validate [file]
+             check the configuration without connecting to the database
+
+Options:
+`},
+	"Chemin du fichier de configuration":                                 {"", "Path of the configuration file"},
+	"Ignorer le checkpoint et repartir du début de la table":             {"", "Ignore the checkpoint and start again from the beginning of the table"},
+	"Remplacer les objets de même nom créés avant les marqueurs paypayo": {"", "Replace same-name objects created before the paypayo markers"},
+	"Fichier de sortie (sortie standard par défaut)":                     {"", "Output file (standard output by default)"},
+	"Commande inconnue: %s\n\n":                                          {"", "Unknown command: %s\n\n"},
+	"Erreur chargement configuration: %v\n":                              {"", "Configuration loading error: %v\n"},
+	"Erreur initialisation logger: %v\n":                                 {"", "Logger initialization error: %v\n"},
+	"Erreur initialisation notifier: %v\n":                               {"", "Notifier initialization error: %v\n"},
+	"Erreur initialisation listener: %v\n":                               {"", "Listener initialization error: %v\n"},
+	"Erreur suppression checkpoint: %v\n":                                {"", "Error removing checkpoint: %v\n"},
+	"Erreur réouverture du fichier de log: %v\n":                         {"", "Error reopening log file: %v\n"},
+	"\n╔════════════════════════════════════════════════════════════════╗\n║ 🚀 Paypayo: DB Listerner - application de surveillance de table ║\n╚════════════════════════════════════════════════════════════════╝\n": {"",
+		"\n╔════════════════════════════════════════════════════════════════╗\n║ 🚀 Paypayo: DB Listener - table monitoring application          ║\n╚════════════════════════════════════════════════════════════════╝\n"},
+	// Message par défaut des messageries (notifier.messagePreset)
+	"{{b}}{{.Operation}}{{b}} sur {{.Table}}\n{{- if .Changed}}{{range .Changed}}\n• {{.}}: {{value (index $.OldData .)}} → {{value (index $.Data .)}}{{end}}\n{{- else}}{{range $k, $v := .Data}}\n• {{$k}}: {{value $v}}{{end}}{{end}}": {"",
+		"{{b}}{{.Operation}}{{b}} on {{.Table}}\n{{- if .Changed}}{{range .Changed}}\n• {{.}}: {{value (index $.OldData .)}} → {{value (index $.Data .)}}{{end}}\n{{- else}}{{range $k, $v := .Data}}\n• {{$k}}: {{value $v}}{{end}}{{end}}"},
+
+	"📊 Configuration:\n":                                {"", "📊 Configuration:\n"},
+	"   └─ Base de données : %s\n":                      {"", "   └─ Database type  : %s\n"},
+	"   └─ Fichier        : %s\n":                       {"", "   └─ File           : %s\n"},
+	"   └─ Hôte           : %s:%d\n":                    {"", "   └─ Host           : %s:%d\n"},
+	"   └─ Database       : %s\n":                       {"", "   └─ Database       : %s\n"},
+	"   └─ Table          : %s\n":                       {"", "   └─ Table          : %s\n"},
+	"   └─ SSL Mode       : %s\n":                       {"", "   └─ SSL Mode       : %s\n"},
+	"   └─ HA             : %s\n":                       {"", "   └─ HA             : %s\n"},
+	"🎯 Modes d'écoute:\n":                               {"", "🎯 Listening modes:\n"},
+	"   ✅ INSERT activé\n":                              {"", "   ✅ INSERT enabled\n"},
+	"   ✅ UPDATE activé\n":                              {"", "   ✅ UPDATE enabled\n"},
+	"   ✅ DELETE activé\n":                              {"", "   ✅ DELETE enabled\n"},
+	"   └─ Polling : toutes les %d secondes\n":          {"", "   └─ Polling : every %d seconds\n"},
+	"   └─ Snapshot initial activé\n":                   {"", "   └─ Initial snapshot enabled\n"},
+	"🌐 Webhook:\n":                                      {"", "🌐 Webhook:\n"},
+	"   └─ URL     : %s\n":                              {"", "   └─ URL     : %s\n"},
+	"   └─ Timeout : %ds\n":                             {"", "   └─ Timeout : %ds\n"},
+	"   └─ Format  : %s\n":                              {"", "   └─ Format  : %s\n"},
+	"   └─ Destination : %s (%s) %s\n":                  {"", "   └─ Destination : %s (%s) %s\n"},
+	"   └─ gRPC    : %s\n":                              {"", "   └─ gRPC    : %s\n"},
+	"   └─ Push    : %s\n":                              {"", "   └─ Push    : %s\n"},
+	"⚙️  Workers:\n":                                    {"", "⚙️  Workers:\n"},
+	"   └─ Pool size : %d workers\n":                    {"", "   └─ Pool size : %d workers\n"},
+	"📝 Logs:\n":                                         {"", "📝 Logs:\n"},
+	"   └─ Retries : %d tentatives\n":                   {"", "   └─ Retries : %d attempts\n"},
+	"   └─ Flux    : %s/events (SSE, WebSocket)\n":      {"", "   └─ Stream  : %s/events (SSE, WebSocket)\n"},
+	"   └─ File      : %d événements (politique: %s)\n": {"", "   └─ Queue     : %d events (policy: %s)\n"},
+	"   └─ Sorties : %s\n":                              {"", "   └─ Outputs : %s\n"},
+	"   └─ Fichier : %s\n":                              {"", "   └─ File    : %s\n"},
+	"   └─ Niveau  : %s\n":                              {"", "   └─ Level   : %s\n"},
+	"   └─ Langue  : %s\n":                              {"", "   └─ Language: %s\n"},
+	"✨ Application démarrée avec succès!":               {"", "✨ Application started successfully!"},
+	"👀 Surveillance active sur la table '%s'\n":         {"", "👀 Watching table '%s'\n"},
+	"📡 En attente d'événements...":                      {"", "📡 Waiting for events..."},
+	"💡 Conseil: Pour exécuter en arrière-plan, utilisez 'nohup' ou 'systemd'": {"", "💡 Tip: to run in the background, use 'nohup' or 'systemd'"},
+	"   Exemple: nohup ./paypayo-1.0.0 &":                                     {"", "   Example: nohup ./paypayo-1.0.0 &"},
+	"🔁 Pour recharger la configuration: kill -SIGHUP <PID>":                   {"", "🔁 To reload the configuration: kill -SIGHUP <PID>"},
+	"📝 Pour rouvrir le fichier de log (logrotate): kill -SIGUSR1 <PID>":       {"", "📝 To reopen the log file (logrotate): kill -SIGUSR1 <PID>"},
+	"⏹️  Pour arrêter: Ctrl+C ou kill -SIGTERM <PID>":                         {"", "⏹️  To stop: Ctrl+C or kill -SIGTERM <PID>"},
+	"\n🛑 Signal d'arrêt reçu...":                                              {"", "\n🛑 Shutdown signal received..."},
+	"\n❌ Erreur: %v\n":                                                        {"", "\n❌ Error: %v\n"},
+	"🔄 Fermeture en cours...":                                                 {"", "🔄 Shutting down..."},
+	"✅ Application arrêtée proprement":                                        {"", "✅ Application stopped cleanly"},
+	"📸 Snapshot de la table '%s' en cours...\n":                               {"", "📸 Snapshot of table '%s' in progress...\n"},
+	"❌ Erreur snapshot: %v\n":                                                 {"", "❌ Snapshot error: %v\n"},
+	"   Relancez la commande pour reprendre au dernier checkpoint.":           {"", "   Run the command again to resume from the last checkpoint."},
+	"✅ Snapshot terminé":                                                      {"", "✅ Snapshot finished"},
+	"❌ Erreur installation: %v\n":                                             {"", "❌ Installation error: %v\n"},
+	"✅ Objets paypayo installés pour la table '%s'\n":                         {"", "✅ paypayo objects installed for table '%s'\n"},
+	"❌ Erreur désinstallation: %v\n":                                          {"", "❌ Uninstallation error: %v\n"},
+	"✅ Objets paypayo supprimés pour la table '%s'\n":                         {"", "✅ paypayo objects removed for table '%s'\n"},
+	"❌ Erreur génération SQL: %v\n":                                           {"", "❌ SQL generation error: %v\n"},
+	"Usage: paypayo [-config config.yaml] config validate [fichier]":          {"", "Usage: paypayo [-config config.yaml] config validate [file]"},
+	"✅ %s: configuration valide\n":                                            {"", "✅ %s: valid configuration\n"},
+	"Objets paypayo pour la table %s (modes: %s)":                             {"", "paypayo objects for table %s (modes: %s)"},
+	"Objets paypayo pour la table %s (capture CDC %s)":                        {"", "paypayo objects for table %s (CDC capture %s)"},
+	"-- %s\n-- Généré par 'paypayo generate-sql', à appliquer avec un utilisateur disposant des droits DDL.\n": {"",
+		"-- %s\n-- Generated by 'paypayo generate-sql', to apply with a user holding DDL privileges.\n"},
+
+	// Configuration
+	"configuration invalide (%d problème(s)):\n  - %s":                                            {"", "invalid configuration (%d problem(s)):\n  - %s"},
+	"ligne %d: champ inconnu %s":                                                                  {"", "line %d: unknown field %s"},
+	" (vouliez-vous dire %s ?)":                                                                   {"", " (did you mean %s?)"},
+	"database.type: %q inconnu, valeurs possibles: %s":                                            {"", "database.type: unknown %q, possible values: %s"},
+	"database.host: obligatoire":                                                                  {"", "database.host: required"},
+	"database.port: %d hors de la plage 1-65535":                                                  {"", "database.port: %d out of range 1-65535"},
+	"database.user: obligatoire":                                                                  {"", "database.user: required"},
+	"database.database: obligatoire":                                                              {"", "database.database: required"},
+	"database.table: obligatoire":                                                                 {"", "database.table: required"},
+	"database.schema: %q contredit le schéma de database.table %q":                                {"", "database.schema: %q contradicts the schema of database.table %q"},
+	"database.sslmode: %q inconnu, valeurs possibles: %s":                                         {"", "database.sslmode: unknown %q, possible values: %s"},
+	"database.sslmode: %q inconnu pour sqlserver, valeurs possibles: %s":                          {"", "database.sslmode: unknown %q for sqlserver, possible values: %s"},
+	"database.table: schéma non supporté avec sqlite, indiquez le nom seul":                       {"", "database.table: schema not supported with sqlite, give the table name only"},
+	"ha.mode: leader non disponible avec sqlite (base locale, instance unique)":                   {"", "ha.mode: leader not available with sqlite (local database, single instance)"},
+	"audit.archive et audit.partition_by_day: non disponibles avec sqlite":                        {"", "audit.archive and audit.partition_by_day: not available with sqlite"},
+	"listener.modes: au moins un mode parmi %s est requis":                                        {"", "listener.modes: at least one mode among %s is required"},
+	"listener.modes: mode %q inconnu, valeurs possibles: %s":                                      {"", "listener.modes: unknown mode %q, possible values: %s"},
+	"listener.poll_interval: %d, doit être d'au moins 1 seconde":                                  {"", "listener.poll_interval: %d, must be at least 1 second"},
+	"listener.max_poll_interval: %d, doit être supérieur ou égal à poll_interval (%d)":            {"", "listener.max_poll_interval: %d, must be greater than or equal to poll_interval (%d)"},
+	"listener.batch_size: %d, doit être positif":                                                  {"", "listener.batch_size: %d, must be positive"},
+	"listener.snapshot_chunk_size: %d, doit être positif":                                         {"", "listener.snapshot_chunk_size: %d, must be positive"},
+	"listener.snapshot_checkpoint: obligatoire":                                                   {"", "listener.snapshot_checkpoint: required"},
+	"listener.schema_check_interval: %d, ne peut pas être négatif":                                {"", "listener.schema_check_interval: %d, cannot be negative"},
+	"webhook.timeout: %d, doit être d'au moins 1 seconde":                                         {"", "webhook.timeout: %d, must be at least 1 second"},
+	"webhook.retry_count: %d, ne peut pas être négatif":                                           {"", "webhook.retry_count: %d, cannot be negative"},
+	"webhook.retry_delay: %d, ne peut pas être négatif":                                           {"", "webhook.retry_delay: %d, cannot be negative"},
+	"webhook.format: %q inconnu, valeurs possibles: %s":                                           {"", "webhook.format: unknown %q, possible values: %s"},
+	"webhook.cloudevents_mode: %q inconnu, valeurs possibles: %s":                                 {"", "webhook.cloudevents_mode: unknown %q, possible values: %s"},
+	"webhook.cloudevents_source: %q invalide: %v":                                                 {"", "webhook.cloudevents_source: invalid %q: %v"},
+	"logging.outputs: au moins une sortie requise (%s)":                                           {"", "logging.outputs: at least one output required (%s)"},
+	"logging.outputs: %q inconnue, valeurs possibles: %s":                                         {"", "logging.outputs: unknown %q, possible values: %s"},
+	"logging.file: obligatoire avec la sortie file":                                               {"", "logging.file: required with the file output"},
+	"logging.rotation: les valeurs doivent être positives ou nulles":                              {"", "logging.rotation: values must be zero or positive"},
+	"logging.syslog.address: obligatoire avec network %s":                                         {"", "logging.syslog.address: required with network %s"},
+	"logging.syslog.network: %q inconnu, valeurs possibles: udp, tcp (vide pour le syslog local)": {"", "logging.syslog.network: unknown %q, possible values: udp, tcp (empty for local syslog)"},
+	"logging.level: %q inconnu, valeurs possibles: %s":                                            {"", "logging.level: unknown %q, possible values: %s"},
+	"logging.format: %q inconnu, valeurs possibles: %s":                                           {"", "logging.format: unknown %q, possible values: %s"},
+	"logging.language: %q inconnue, valeurs possibles: %s":                                        {"", "logging.language: unknown %q, possible values: %s"},
+	"worker.pool_size: %d, au moins 1 worker est requis":                                          {"", "worker.pool_size: %d, at least 1 worker is required"},
+	"worker.queue_size: %d, doit être positif":                                                    {"", "worker.queue_size: %d, must be positive"},
+	"worker.queue_policy: %q inconnue, valeurs possibles: %s":                                     {"", "worker.queue_policy: unknown %q, possible values: %s"},
+	"worker.spill_dir: obligatoire avec queue_policy spill_to_disk":                               {"", "worker.spill_dir: required with queue_policy spill_to_disk"},
+	"audit.retention_hours: %d, ne peut pas être négatif":                                         {"", "audit.retention_hours: %d, cannot be negative"},
+	"audit.cleanup_interval: %d, doit être d'au moins 1 seconde":                                  {"", "audit.cleanup_interval: %d, must be at least 1 second"},
+	"audit.batch_size: %d, doit être positif":                                                     {"", "audit.batch_size: %d, must be positive"},
+	"ha.mode: %q inconnu, valeurs possibles: %s":                                                  {"", "ha.mode: unknown %q, possible values: %s"},
+	"ha.mode: shared n'est disponible qu'avec MySQL (utilisez leader)":                            {"", "ha.mode: shared is only available with MySQL (use leader)"},
+	"ha.retry_interval: %d, doit être d'au moins 1 seconde":                                       {"", "ha.retry_interval: %d, must be at least 1 second"},
+	"stream.buffer_size: %d, doit être positif":                                                   {"", "stream.buffer_size: %d, must be positive"},
+	"stream.client_buffer: %d, doit être positif":                                                 {"", "stream.client_buffer: %d, must be positive"},
+	"stream.journal_max_mb: %d, doit être positif":                                                {"", "stream.journal_max_mb: %d, must be positive"},
+	"grpc.addr: %q invalide, format attendu hôte:port":                                            {"", "grpc.addr: invalid %q, expected host:port"},
+	"grpc.tls_cert et grpc.tls_key: doivent être renseignés ensemble":                             {"", "grpc.tls_cert and grpc.tls_key: must be set together"},
+	"grpc.push_timeout: %d, doit être d'au moins 1 seconde":                                       {"", "grpc.push_timeout: %d, must be at least 1 second"},
+	"grpc.push_retry_count: %d, ne peut pas être négatif":                                         {"", "grpc.push_retry_count: %d, cannot be negative"},
+	"grpc.push_retry_delay: %d, ne peut pas être négatif":                                         {"", "grpc.push_retry_delay: %d, cannot be negative"},
+	"http.addr: %q invalide, format attendu hôte:port":                                            {"", "http.addr: invalid %q, expected host:port"},
+	"http.tls_cert et http.tls_key: doivent être renseignés ensemble":                             {"", "http.tls_cert and http.tls_key: must be set together"},
+	"metrics.addr: %q invalide, format attendu hôte:port":                                         {"", "metrics.addr: invalid %q, expected host:port"},
+	"%s.name: %q déjà utilisé":                                                                    {"", "%s.name: %q already used"},
+	"%s.type: %q inconnu, valeurs possibles: %s":                                                  {"", "%s.type: unknown %q, possible values: %s"},
+	"%s.url: obligatoire pour le type %s (url du webhook entrant)":                                {"", "%s.url: required for type %s (incoming webhook url)"},
+	"%s: token et chat_id sont obligatoires pour le type telegram":                                {"", "%s: token and chat_id are required for type telegram"},
+	"%s.body: non utilisé par le type %s, utilisez message":                                       {"", "%s.body: not used by type %s, use message"},
+	"%s.message: réservé aux types slack, teams, discord et telegram":                             {"", "%s.message: reserved for types slack, teams, discord and telegram"},
+	"%s.operations: %q inconnue, valeurs possibles: %s":                                           {"", "%s.operations: unknown %q, possible values: %s"},
+	"%s.%s: template invalide: %v":                                                                {"", "%s.%s: invalid template: %v"},
+	"ligne %d: %w":                                                                                {"", "line %d: %w"},
+	"ligne %d: %s: %w":                                                                            {"", "line %d: %s: %w"},
+	"accolade fermante manquante dans %q":                                                         {"", "missing closing brace in %q"},
+	"variable d'environnement %s non définie":                                                     {"", "environment variable %s not set"},
+	"erreur lecture fichier secret: %w":                                                           {"", "error reading secret file: %w"},
+	"entier attendu: %q":                                                                          {"", "integer expected: %q"},
+	"booléen attendu: %q":                                                                         {"", "boolean expected: %q"},
+	"type non supporté par les variables d'environnement":                                         {"", "type not supported by environment variables"},
+	"obligatoire":                                     {"", "required"},
+	"%q invalide: %v":                                 {"", "invalid %q: %v"},
+	"%q: schéma http ou https attendu":                {"", "%q: http or https scheme expected"},
+	"%q: hôte manquant":                               {"", "%q: missing host"},
+	"erreur lecture fichier config: %w":               {"", "error reading config file: %w"},
+	"erreur parsing config: %w":                       {"", "error parsing config: %w"},
+	"erreur résolution config: %w":                    {"", "error resolving config: %w"},
+	"erreur variables d'environnement: %w":            {"", "environment variables error: %w"},
+	"identifiant vide":                                {"", "empty identifier"},
+	"identifiant %q: UTF-8 invalide":                  {"", "identifier %q: invalid UTF-8"},
+	"identifiant %q: caractère NUL interdit":          {"", "identifier %q: NUL character not allowed"},
+	"identifiant %q: %d octets, %d maximum":           {"", "identifier %q: %d bytes, %d maximum"},
+	"identifiant %q: espace final interdit par MySQL": {"", "identifier %q: trailing space not allowed by MySQL"},
+	"nom %q: guillemet fermant manquant":              {"", "name %q: missing closing quote"},
+	"nom %q: partie vide":                             {"", "name %q: empty part"},
+	"nom %q: caractère inattendu après %q":            {"", "name %q: unexpected character after %q"},
+	"nom %q: format attendu table ou schema.table":    {"", "name %q: expected table or schema.table"},
+
+	// Erreurs: base de données
+	"type de base de données non supporté: %s":    {"", "unsupported database type: %s"},
+	"nom de table invalide: %w":                   {"", "invalid table name: %w"},
+	"erreur connexion PostgreSQL: %w":             {"", "PostgreSQL connection error: %w"},
+	"erreur ping PostgreSQL: %w":                  {"", "PostgreSQL ping error: %w"},
+	"erreur connexion MySQL: %w":                  {"", "MySQL connection error: %w"},
+	"erreur ping MySQL: %w":                       {"", "MySQL ping error: %w"},
+	"erreur ouverture SQLite: %w":                 {"", "SQLite open error: %w"},
+	"erreur ping SQLite: %w":                      {"", "SQLite ping error: %w"},
+	"erreur connexion SQL Server: %w":             {"", "SQL Server connection error: %w"},
+	"erreur ping SQL Server: %w":                  {"", "SQL Server ping error: %w"},
+	"erreur lecture de la version du serveur: %w": {"", "error reading server version: %w"},
+	"%s non supporté: TiDB n'implémente pas les triggers, utilisez TiCDC pour répliquer la table vers MySQL ou MariaDB": {"",
+		"%s not supported: TiDB does not implement triggers, use TiCDC to replicate the table to MySQL or MariaDB"},
+	"%s non supporté: MariaDB 10.3.10 ou plus récent requis":          {"", "%s not supported: MariaDB 10.3.10 or later required"},
+	"ha.mode shared nécessite MariaDB 10.6 (SKIP LOCKED), serveur %s": {"", "ha.mode shared requires MariaDB 10.6 (SKIP LOCKED), server %s"},
+	"%s non supporté: MySQL 5.7.8 ou plus récent requis":              {"", "%s not supported: MySQL 5.7.8 or later required"},
+	"ha.mode shared nécessite MySQL 8.0.1 (SKIP LOCKED), serveur %s":  {"", "ha.mode shared requires MySQL 8.0.1 (SKIP LOCKED), server %s"},
+	"erreur setup triggers: %w":                                       {"", "trigger setup error: %w"},
+	"erreur setup audit: %w":                                          {"", "audit setup error: %w"},
+	"erreur setup CDC: %w":                                            {"", "CDC setup error: %w"},
+	"erreur lecture types des colonnes: %w":                           {"", "error reading column types: %w"},
+	"erreur création file d'événements: %w":                           {"", "error creating event queue: %w"},
+	"erreur LISTEN: %w":                                               {"", "LISTEN error: %w"},
+	"erreur LISTEN snapshot: %w":                                      {"", "snapshot LISTEN error: %w"},
+	"erreur récupération colonnes: %w":                                {"", "error fetching columns: %w"},
+	"erreur lecture colonnes: %w":                                     {"", "error reading columns: %w"},
+	"erreur récupération colonnes JSON: %w":                           {"", "error fetching JSON columns: %w"},
+	"erreur lecture colonnes JSON: %w":                                {"", "error reading JSON columns: %w"},
+	"aucune colonne trouvée pour la table %s":                         {"", "no column found for table %s"},
+	"erreur création registre %s: %w":                                 {"", "error creating registry %s: %w"},
+	"erreur query audit: %w":                                          {"", "audit query error: %w"},
+	"erreur transaction audit: %w":                                    {"", "audit transaction error: %w"},
+	"erreur commit audit: %w":                                         {"", "audit commit error: %w"},
+	"erreur marquage processed: %w":                                   {"", "error marking rows processed: %w"},
+	"erreur lecture état %s: %w":                                      {"", "error reading state of %s: %w"},
+	"%s n'a pas été créé par paypayo":                                 {"", "%s was not created by paypayo"},
+	"%s absent":                                                       {"", "%s missing"},
+	"%s n'est pas à jour":                                             {"", "%s is not up to date"},
+	"erreur verrouillage de %s: %w":                                   {"", "error locking %s: %w"},
+	"erreur suppression %s: %w":                                       {"", "error removing %s: %w"},
+	"erreur création %s: %w":                                          {"", "error creating %s: %w"},
+	"erreur mise à jour %s: %w":                                       {"", "error updating %s: %w"},
+	"verrou d'installation %s non obtenu après 60 secondes":           {"", "installation lock %s not acquired after 60 seconds"},
+	"%s existe déjà et n'a pas été créé par paypayo; renommez-le, supprimez-le ou lancez 'paypayo install -adopt' pour le remplacer": {"",
+		"%s already exists and was not created by paypayo; rename it, drop it or run 'paypayo install -adopt' to replace it"},
+	"objets paypayo manquants ou périmés (manage_triggers désactivé), appliquez le SQL de 'paypayo generate-sql':\n  - %s": {"",
+		"paypayo objects missing or outdated (manage_triggers disabled), apply the SQL from 'paypayo generate-sql':\n  - %s"},
+	"erreur régénération triggers: %w":                                 {"", "trigger regeneration error: %w"},
+	"erreur partitionnement: %w":                                       {"", "partitioning error: %w"},
+	"erreur archivage: %w":                                             {"", "archiving error: %w"},
+	"erreur création partition %s: %w":                                 {"", "error creating partition %s: %w"},
+	"erreur archivage partition %s: %w":                                {"", "error archiving partition %s: %w"},
+	"erreur suppression partition %s: %w":                              {"", "error dropping partition %s: %w"},
+	"erreur lecture position CDC: %w":                                  {"", "error reading CDC position: %w"},
+	"erreur lecture colonnes capturées: %w":                            {"", "error reading captured columns: %w"},
+	"instance de capture %s introuvable ou sans colonne":               {"", "capture instance %s not found or without columns"},
+	"erreur lecture des changements CDC: %w":                           {"", "error reading CDC changes: %w"},
+	"erreur scan changement: %w":                                       {"", "change scan error: %w"},
+	"erreur lecture des LSN CDC: %w":                                   {"", "error reading CDC LSNs: %w"},
+	"erreur lecture du LSN CDC: %w":                                    {"", "error reading CDC LSN: %w"},
+	"capture CDC en retard de plus de %s (Agent SQL Server démarré ?)": {"", "CDC capture lagging by more than %s (is SQL Server Agent running?)"},
+	"erreur lecture fenêtre CDC: %w":                                   {"", "error reading CDC window: %w"},
+	"erreur connexion verrou: %w":                                      {"", "lock connection error: %w"},
+	"verrou %s perdu: %w":                                              {"", "lock %s lost: %w"},
+	"verrou non détenu":                                                {"", "lock not held"},
+	"instance passive, snapshot impossible":                            {"", "passive instance, snapshot not possible"},
+
+	// Erreurs: snapshot
+	"erreur lecture clé primaire: %w":                          {"", "error reading primary key: %w"},
+	"la table %s n'a pas de clé primaire, snapshot impossible": {"", "table %s has no primary key, snapshot not possible"},
+	"erreur lecture tranche: %w":                               {"", "error reading chunk: %w"},
+	"erreur mise en file snapshot: %w":                         {"", "snapshot queueing error: %w"},
+	"erreur notification snapshot: %w":                         {"", "snapshot notification error: %w"},
+	"erreur lecture checkpoint: %w":                            {"", "error reading checkpoint: %w"},
+	"checkpoint %s illisible: %w":                              {"", "unreadable checkpoint %s: %w"},
+	"erreur écriture checkpoint: %w":                           {"", "error writing checkpoint: %w"},
+	"watermark %s non reçu après %s":                           {"", "watermark %s not received after %s"},
+	"erreur émission watermark: %w":                            {"", "error emitting watermark: %w"},
+	"erreur lecture fenêtre de watermarks: %w":                 {"", "error reading watermark window: %w"},
+	"erreur écriture watermark: %w":                            {"", "error writing watermark: %w"},
+
+	// Erreurs: file, notifications et flux
+	"politique de file inconnue: %s":                {"", "unknown queue policy: %s"},
+	"file d'événements pleine, événement perdu":     {"", "event queue full, event dropped"},
+	"fichier de débordement fermé":                  {"", "spill file closed"},
+	"erreur création répertoire de débordement: %w": {"", "error creating spill directory: %w"},
+	"erreur ouverture fichier de débordement: %w":   {"", "error opening spill file: %w"},
+	"erreur lecture fichier de débordement: %w":     {"", "error reading spill file: %w"},
+	"erreur écriture fichier de débordement: %w":    {"", "error writing spill file: %w"},
+	"push gRPC: %w":                                  {"", "gRPC push: %w"},
+	"erreur marshalling événement: %w":               {"", "error marshalling event: %w"},
+	"événement illisible sur disque: %w":             {"", "unreadable event on disk: %w"},
+	"erreur template when: %w":                       {"", "when template error: %w"},
+	"erreur template message: %w":                    {"", "message template error: %w"},
+	"erreur marshalling JSON: %w":                    {"", "JSON marshalling error: %w"},
+	"erreur template body: %w":                       {"", "body template error: %w"},
+	"erreur template en-tête %s: %w":                 {"", "header %s template error: %w"},
+	"erreur template path: %w":                       {"", "path template error: %w"},
+	"erreur template query: %w":                      {"", "query template error: %w"},
+	"query %q invalide: %w":                          {"", "invalid query %q: %w"},
+	"erreur compilation des templates: %w":           {"", "template compilation error: %w"},
+	"erreur création requête: %w":                    {"", "request creation error: %w"},
+	"erreur envoi requête: %w":                       {"", "request send error: %w"},
+	"statut HTTP %d":                                 {"", "HTTP status %d"},
+	"destination %d":                                 {"", "destination %d"},
+	"erreur connexion gRPC %s: %w":                   {"", "gRPC connection error %s: %w"},
+	"erreur conversion événement: %w":                {"", "event conversion error: %w"},
+	"erreur conversion événement %d: %v":             {"", "event conversion error %d: %v"},
+	"erreur chargement certificat gRPC: %w":          {"", "error loading gRPC certificate: %w"},
+	"erreur écoute gRPC: %w":                         {"", "gRPC listen error: %w"},
+	"erreur écoute HTTP: %w":                         {"", "HTTP listen error: %w"},
+	"last_event_id %q invalide: offset attendu":      {"", "invalid last_event_id %q: offset expected"},
+	"méthode non autorisée":                          {"", "method not allowed"},
+	"jeton absent ou invalide":                       {"", "missing or invalid token"},
+	"streaming non supporté":                         {"", "streaming not supported"},
+	"position de reprise inconnue ou plus conservée": {"", "resume position unknown or no longer retained"},
+	"abonné trop lent, flux interrompu":              {"", "subscriber too slow, stream interrupted"},
+	"flux fermé":                                     {"", "stream closed"},
+	"erreur ouverture journal: %w":                   {"", "error opening journal: %w"},
+	"erreur rotation journal: %w":                    {"", "journal rotation error: %w"},
+	"erreur lecture journal: %w":                     {"", "error reading journal: %w"},
+	"erreur écriture journal: %w":                    {"", "error writing journal: %w"},
+
+	// Erreurs: logs
+	"erreur connexion syslog: %w":      {"", "syslog connection error: %w"},
+	"sortie de log inconnue: %s":       {"", "unknown log output: %s"},
+	"aucune sortie de log configurée":  {"", "no log output configured"},
+	"erreur ouverture fichier log: %w": {"", "error opening log file: %w"},
+}
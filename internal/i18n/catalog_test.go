@@ -0,0 +1,147 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+	codePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+	letters     = regexp.MustCompile(`\pL`)
+	// Préfixes sans texte à traduire: clé de configuration, variable
+	// d'environnement.
+	neutral = regexp.MustCompile(`^((%s|[a-z_]+)(\.[a-z_]+)*|%s_FILE): %[vw]$`)
+)
+
+// logMethods sont les méthodes de logger.Logger; leur format reçoit un code.
+var logMethods = map[string]bool{"Debug": true, "Info": true, "Warn": true, "Error": true}
+
+// i18nFuncs donnent, pour chaque fonction du paquet, la position du texte.
+var i18nFuncs = map[string]int{
+	"T": 0, "Sprintf": 0, "Errorf": 0, "Printf": 0, "Println": 0, "NewError": 0,
+	"Fprintf": 1, "Fprintln": 1,
+}
+
+// message est un texte littéral passé à un logger ou au paquet i18n.
+type message struct {
+	pos  token.Position
+	text string
+	log  bool
+}
+
+// sourceMessages parcourt les sources du module, hors tests et code généré.
+func sourceMessages(t *testing.T) []message {
+	t.Helper()
+
+	root := filepath.Join("..", "..")
+	fset := token.NewFileSet()
+	var messages []message
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "paypayov1") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			arg, isLog := -1, false
+			switch fn := call.Fun.(type) {
+			case *ast.SelectorExpr:
+				if pkg, ok := fn.X.(*ast.Ident); ok && pkg.Name == "i18n" {
+					if i, ok := i18nFuncs[fn.Sel.Name]; ok {
+						arg = i
+					}
+				} else if logMethods[fn.Sel.Name] {
+					arg, isLog = 0, true
+				}
+			case *ast.Ident:
+				// add: problèmes de validation de la configuration, traduits
+				// par un i18n.Printer.
+				if fn.Name == "add" {
+					arg = 0
+				}
+			}
+			if arg < 0 || arg >= len(call.Args) {
+				return true
+			}
+
+			lit, ok := call.Args[arg].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			text, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Errorf("%s: %v", fset.Position(lit.Pos()), err)
+				return true
+			}
+			messages = append(messages, message{pos: fset.Position(lit.Pos()), text: text, log: isLog})
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestCatalogCoversSources(t *testing.T) {
+	messages := sourceMessages(t)
+	if len(messages) < 100 {
+		t.Fatalf("%d messages trouvés, parcours des sources incomplet", len(messages))
+	}
+
+	for _, m := range messages {
+		e, ok := catalog[m.text]
+		switch {
+		case m.log && !ok:
+			t.Errorf("%s: message de log absent du catalogue: %q", m.pos, m.text)
+		case m.log && e.code == "":
+			t.Errorf("%s: message de log sans code: %q", m.pos, m.text)
+		case !ok && !neutral.MatchString(m.text) && letters.MatchString(verbPattern.ReplaceAllString(m.text, "")):
+			t.Errorf("%s: texte absent du catalogue: %q", m.pos, m.text)
+		}
+	}
+}
+
+func TestCatalogEntries(t *testing.T) {
+	for text, e := range catalog {
+		if e.en == "" {
+			t.Errorf("traduction anglaise manquante: %q", text)
+		}
+		if e.code != "" && !codePattern.MatchString(e.code) {
+			t.Errorf("code %q invalide pour %q", e.code, text)
+		}
+		// Les arguments sont passés dans l'ordre du texte source.
+		fr := strings.Join(verbPattern.FindAllString(text, -1), " ")
+		en := strings.Join(verbPattern.FindAllString(e.en, -1), " ")
+		if fr != en {
+			t.Errorf("verbes différents pour %q: %s (fr) / %s (en)", text, fr, en)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package i18n traduit les messages de l'application (logs, erreurs,
+// console). Le français est la langue source: le texte écrit dans le code
+// sert de clé au catalogue anglais et au code stable des messages de log.
+package i18n
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+const (
+	French  = "fr"
+	English = "en"
+)
+
+// Languages liste les langues disponibles.
+var Languages = []string{French, English}
+
+var english atomic.Bool
+
+// entry associe à un texte source sa traduction anglaise et, pour les
+// messages de log, un code stable utilisable par les règles d'alerte.
+type entry struct {
+	code string
+	en   string
+}
+
+// SetLanguage choisit la langue des messages; une valeur inconnue ou vide
+// laisse le français.
+func SetLanguage(lang string) {
+	english.Store(lang == English)
+}
+
+// Printer traduit vers une langue donnée, indépendamment de SetLanguage.
+type Printer struct {
+	english bool
+}
+
+func For(lang string) Printer {
+	return Printer{english: lang == English}
+}
+
+func current() Printer {
+	return Printer{english: english.Load()}
+}
+
+// T renvoie la traduction du texte, ou le texte lui-même s'il n'est pas au
+// catalogue.
+func (p Printer) T(msg string) string {
+	if p.english {
+		if e, ok := catalog[msg]; ok && e.en != "" {
+			return e.en
+		}
+	}
+	return msg
+}
+
+func (p Printer) Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(p.T(format), a...)
+}
+
+func T(msg string) string {
+	return current().T(msg)
+}
+
+func Sprintf(format string, a ...interface{}) string {
+	return current().Sprintf(format, a...)
+}
+
+// Errorf est l'équivalent traduit de fmt.Errorf (%w compris).
+func Errorf(format string, a ...interface{}) error {
+	return fmt.Errorf(T(format), a...)
+}
+
+func Printf(format string, a ...interface{}) {
+	fmt.Printf(T(format), a...)
+}
+
+func Println(msg string) {
+	fmt.Println(T(msg))
+}
+
+func Fprintf(w io.Writer, format string, a ...interface{}) {
+	fmt.Fprintf(w, T(format), a...)
+}
+
+func Fprintln(w io.Writer, msg string) {
+	fmt.Fprintln(w, T(msg))
+}
+
+// Code renvoie le code stable d'un message de log, vide s'il n'en a pas.
+func Code(msg string) string {
+	return catalog[msg].code
+}
+
+// NewError crée une erreur sentinelle traduite à l'affichage: déclarée au
+// chargement du paquet, elle suit la langue choisie ensuite.
+func NewError(msg string) error {
+	return &sentinel{msg: msg}
+}
+
+type sentinel struct {
+	msg string
+}
+
+func (e *sentinel) Error() string {
+	return T(e.msg)
+}
@@ -1,104 +1,202 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
-)
 
-type Level int
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+)
 
+// Champs structurés ajoutés aux messages par With.
 const (
-	DEBUG Level = iota
-	INFO
-	WARN
-	ERROR
+	FieldTable      = "table"
+	FieldOperation  = "operation"
+	FieldEventID    = "event_id"
+	FieldAttempt    = "attempt"
+	FieldStatusCode = "status_code"
+	FieldWorkerID   = "worker_id"
+	FieldDuration   = "duration_ms"
+	// Code stable du message, identique quelle que soit la langue.
+	FieldCode = "code"
 )
 
+// Logger écrit des messages au format texte ("[date] NIVEAU: message") ou
+// JSON, une ligne par message, vers une ou plusieurs sorties. Les loggers
+// dérivés par With partagent les sorties et le niveau.
 type Logger struct {
-	file   *os.File
-	logger *log.Logger
-	level  Level
-	mu     sync.Mutex
+	logger  *slog.Logger
+	level   *slog.LevelVar
+	outputs *outputs // nil pour un logger dérivé
 }
 
-func New(filename string, level string) (*Logger, error) {
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+func New(cfg *config.LoggingConfig) (*Logger, error) {
+	level := new(slog.LevelVar)
+	outs, err := openOutputs(cfg, level)
 	if err != nil {
-		return nil, fmt.Errorf("erreur ouverture fichier log: %w", err)
+		return nil, err
 	}
 
 	l := &Logger{
-		file:   file,
-		logger: log.New(file, "", 0),
-		level:  parseLevel(level),
+		logger:  slog.New(outs.handler()),
+		level:   level,
+		outputs: outs,
+	}
+	l.SetLevel(cfg.Level)
+
+	if every := cfg.Rotation.IntervalHours; every > 0 && outs.file != nil {
+		go outs.rotateEvery(time.Duration(every)*time.Hour, l)
 	}
 
 	return l, nil
 }
 
-func parseLevel(level string) Level {
+func parseLevel(level string) slog.Level {
 	switch level {
 	case "debug":
-		return DEBUG
-	case "info":
-		return INFO
+		return slog.LevelDebug
 	case "warn":
-		return WARN
+		return slog.LevelWarn
 	case "error":
-		return ERROR
+		return slog.LevelError
 	default:
-		return INFO
+		return slog.LevelInfo
 	}
 }
 
-func (l *Logger) log(level Level, format string, v ...interface{}) {
-	if level < l.level {
-		return
-	}
-
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// SetLevel change le niveau minimum des messages écrits.
+func (l *Logger) SetLevel(level string) {
+	l.level.Set(parseLevel(level))
+}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	levelStr := ""
+// With renvoie un logger qui ajoute les paires clé, valeur à chaque message,
+// ex: log.With(logger.FieldWorkerID, id).
+func (l *Logger) With(args ...interface{}) *Logger {
+	return &Logger{logger: l.logger.With(args...), level: l.level}
+}
 
-	switch level {
-	case DEBUG:
-		levelStr = "DEBUG"
-	case INFO:
-		levelStr = "INFO"
-	case WARN:
-		levelStr = "WARN"
-	case ERROR:
-		levelStr = "ERROR"
+func (l *Logger) log(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
 	}
-
-	message := fmt.Sprintf(format, v...)
-	l.logger.Printf("[%s] %s: %s\n", timestamp, levelStr, message)
+	msg := fmt.Sprintf(i18n.T(format), v...)
+	if code := i18n.Code(format); code != "" {
+		l.logger.LogAttrs(ctx, level, msg, slog.String(FieldCode, code))
+		return
+	}
+	l.logger.Log(ctx, level, msg)
 }
 
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.log(DEBUG, format, v...)
+	l.log(slog.LevelDebug, format, v...)
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.log(INFO, format, v...)
+	l.log(slog.LevelInfo, format, v...)
 }
 
 func (l *Logger) Warn(format string, v ...interface{}) {
-	l.log(WARN, format, v...)
+	l.log(slog.LevelWarn, format, v...)
 }
 
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.log(ERROR, format, v...)
+	l.log(slog.LevelError, format, v...)
+}
+
+// Reopen ferme le fichier de log, rouvert à la prochaine écriture: après
+// un déplacement par logrotate, l'écriture reprend dans un nouveau fichier.
+func (l *Logger) Reopen() error {
+	if l.outputs == nil || l.outputs.file == nil {
+		return nil
+	}
+	return l.outputs.file.Close()
 }
 
+// Close ferme les sorties; sans effet sur un logger dérivé.
 func (l *Logger) Close() error {
-	if l.file != nil {
-		return l.file.Close()
+	if l.outputs != nil {
+		return l.outputs.close()
 	}
 	return nil
 }
+
+// textHandler conserve le format historique et ajoute les champs en fin de
+// ligne: "[2006-01-02 15:04:05] INFO: message table=orders attempt=2".
+// Vers syslog, qui date les messages et porte le niveau, seul le message et
+// ses champs sont écrits.
+type textHandler struct {
+	write  func(level slog.Level, line string) error
+	level  slog.Leveler
+	plain  bool
+	mu     *sync.Mutex
+	attrs  []slog.Attr
+	prefix string // groupes ouverts par WithGroup
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !h.plain {
+		fmt.Fprintf(&b, "[%s] %s: ", r.Time.Format("2006-01-02 15:04:05"), r.Level)
+	}
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&b, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+	if !h.plain {
+		b.WriteByte('\n')
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.write(r.Level, b.String())
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			writeAttr(b, prefix+a.Key+".", g)
+		}
+		return
+	}
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, value)
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		next.attrs = append(next.attrs, a)
+	}
+	return &next
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+)
+
+// outputs regroupe les sorties ouvertes par New.
+type outputs struct {
+	handlers []slog.Handler
+	file     *lumberjack.Logger
+	syslog   *syslog.Writer
+	stop     chan struct{}
+}
+
+func openOutputs(cfg *config.LoggingConfig, level *slog.LevelVar) (*outputs, error) {
+	outs := &outputs{stop: make(chan struct{})}
+
+	for _, name := range cfg.Outputs {
+		switch name {
+		case "file":
+			f, err := openFile(cfg)
+			if err != nil {
+				outs.close()
+				return nil, err
+			}
+			outs.file = f
+			outs.handlers = append(outs.handlers, newHandler(cfg.Format, f, level))
+		case "stdout":
+			outs.handlers = append(outs.handlers, newHandler(cfg.Format, os.Stdout, level))
+		case "stderr":
+			outs.handlers = append(outs.handlers, newHandler(cfg.Format, os.Stderr, level))
+		case "syslog":
+			w, err := syslog.Dial(cfg.Syslog.Network, cfg.Syslog.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, cfg.Syslog.Tag)
+			if err != nil {
+				outs.close()
+				return nil, i18n.Errorf("erreur connexion syslog: %w", err)
+			}
+			outs.syslog = w
+			outs.handlers = append(outs.handlers, &textHandler{write: syslogWrite(w), level: level, plain: true, mu: new(sync.Mutex)})
+		default:
+			outs.close()
+			return nil, i18n.Errorf("sortie de log inconnue: %s", name)
+		}
+	}
+
+	if len(outs.handlers) == 0 {
+		return nil, i18n.Errorf("aucune sortie de log configurée")
+	}
+	return outs, nil
+}
+
+// openFile ouvre le fichier de log dès le démarrage pour signaler un chemin
+// invalide. Sa taille n'est pas limitée si max_size_mb vaut 0 (lumberjack
+// appliquerait 100 Mo).
+func openFile(cfg *config.LoggingConfig) (*lumberjack.Logger, error) {
+	rot := cfg.Rotation
+	maxSize := rot.MaxSizeMB
+	if maxSize == 0 {
+		maxSize = math.MaxInt32
+	}
+
+	f := &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    maxSize,
+		MaxBackups: rot.MaxBackups,
+		MaxAge:     rot.MaxAgeDays,
+		Compress:   rot.Compress,
+		LocalTime:  true,
+	}
+	if _, err := f.Write(nil); err != nil {
+		return nil, i18n.Errorf("erreur ouverture fichier log: %w", err)
+	}
+	return f, nil
+}
+
+func newHandler(format string, w io.Writer, level *slog.LevelVar) slog.Handler {
+	if format == "json" {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	}
+	return &textHandler{
+		write: func(_ slog.Level, line string) error {
+			_, err := io.WriteString(w, line)
+			return err
+		},
+		level: level,
+		mu:    new(sync.Mutex),
+	}
+}
+
+// syslogWrite choisit la sévérité syslog correspondant au niveau.
+func syslogWrite(w *syslog.Writer) func(slog.Level, string) error {
+	return func(level slog.Level, line string) error {
+		switch {
+		case level >= slog.LevelError:
+			return w.Err(line)
+		case level >= slog.LevelWarn:
+			return w.Warning(line)
+		case level >= slog.LevelInfo:
+			return w.Info(line)
+		default:
+			return w.Debug(line)
+		}
+	}
+}
+
+func (o *outputs) handler() slog.Handler {
+	if len(o.handlers) == 1 {
+		return o.handlers[0]
+	}
+	return multiHandler(o.handlers)
+}
+
+// rotateEvery fait tourner le fichier à intervalle fixe, aligné sur l'heure
+// UTC (minuit pour 24 heures), en plus de la rotation par taille.
+func (o *outputs) rotateEvery(every time.Duration, log *Logger) {
+	for {
+		next := time.Now().Truncate(every).Add(every)
+		select {
+		case <-o.stop:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if err := o.file.Rotate(); err != nil {
+			log.Error("Erreur rotation du fichier de log: %v", err)
+		}
+	}
+}
+
+func (o *outputs) close() error {
+	close(o.stop)
+
+	var errs []error
+	if o.file != nil {
+		errs = append(errs, o.file.Close())
+	}
+	if o.syslog != nil {
+		errs = append(errs, o.syslog.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// multiHandler écrit chaque message dans toutes les sorties.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithGroup(name)
+	}
+	return next
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"sync/atomic"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+)
+
+var (
+	EventsReceived    = expvar.NewInt("events_received")
+	EventsQueued      = expvar.NewInt("events_queued")
+	EventsDropped     = expvar.NewInt("events_dropped")
+	EventsSpilled     = expvar.NewInt("events_spilled")
+	QueueBlocked      = expvar.NewInt("queue_blocked")
+	SpillPending      = expvar.NewInt("spill_pending")
+	SpillLost         = expvar.NewInt("spill_lost") // événements illisibles sur disque
+	QueuePolicy       = expvar.NewString("queue_policy")
+	EventsDelivered   = expvar.NewInt("events_delivered")
+	EventsFailed      = expvar.NewInt("events_failed")
+	SchemaChanges     = expvar.NewInt("schema_changes")
+	AuditRows         = expvar.NewInt("audit_rows") // estimation (information_schema)
+	AuditBytes        = expvar.NewInt("audit_bytes")
+	AuditBacklog      = expvar.NewInt("audit_backlog")
+	AuditPurged       = expvar.NewInt("audit_purged")
+	StreamSubscribers = expvar.NewInt("stream_subscribers")
+)
+
+// queueLength calcule la longueur de la file (mémoire et disque) à chaque
+// lecture de /metrics.
+var queueLength atomic.Pointer[func() int64]
+
+func init() {
+	expvar.Publish("queue_length", expvar.Func(func() any {
+		if f := queueLength.Load(); f != nil {
+			return (*f)()
+		}
+		return int64(0)
+	}))
+}
+
+// SetQueueLength enregistre la fonction qui donne la longueur de la file.
+func SetQueueLength(f func() int64) {
+	queueLength.Store(&f)
+}
+
+// Serve expose les compteurs au format JSON (expvar) sur /metrics.
+func Serve(addr string, log *logger.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", expvar.Handler())
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("Erreur serveur métriques: %v", err)
+		}
+	}()
+
+	log.Info("Métriques exposées sur http://%s/metrics", addr)
+}
+
+func String() string {
+	return i18n.Sprintf("reçus=%d en_file=%d perdus=%d sur_disque=%d bloqués=%d livrés=%d échecs=%d",
+		EventsReceived.Value(), EventsQueued.Value(), EventsDropped.Value(),
+		EventsSpilled.Value(), QueueBlocked.Value(), EventsDelivered.Value(), EventsFailed.Value())
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"app-db-listener/internal/i18n"
+)
+
+// Adresse de l'API Bot Telegram, remplaçable par url (tests, proxy).
+const telegramAPI = "https://api.telegram.org"
+
+// messagePreset est le message par défaut des messageries, traduit dans la
+// langue des logs; {{b}} est remplacé par le marqueur de gras du type.
+const messagePreset = `{{b}}{{.Operation}}{{b}} sur {{.Table}}
+{{- if .Changed}}{{range .Changed}}
+• {{.}}: {{value (index $.OldData .)}} → {{value (index $.Data .)}}{{end}}
+{{- else}}{{range $k, $v := .Data}}
+• {{$k}}: {{value $v}}{{end}}{{end}}`
+
+// chatBold donne le marqueur de gras de chaque messagerie; Telegram reçoit
+// du texte brut pour éviter l'échappement de MarkdownV2.
+var chatBold = map[string]string{
+	"slack":    "*",
+	"teams":    "**",
+	"discord":  "**",
+	"telegram": "",
+}
+
+// Longueur maximale d'un message, au-delà il est tronqué.
+var chatLimits = map[string]int{
+	"slack":    40000,
+	"teams":    20000,
+	"discord":  2000,
+	"telegram": 4096,
+}
+
+func chatPreset(kind string) string {
+	return strings.ReplaceAll(i18n.T(messagePreset), "{{b}}", chatBold[kind])
+}
+
+// chatBody met le texte dans le corps JSON attendu par la messagerie.
+func chatBody(kind, text, chatID string) ([]byte, error) {
+	text = truncate(text, chatLimits[kind])
+
+	var payload interface{}
+	switch kind {
+	case "slack":
+		payload = map[string]string{"text": text}
+	case "teams":
+		// Le markdown des connecteurs ignore les retours à la ligne simples
+		text = strings.ReplaceAll(text, "\n", "\n\n")
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  strings.ReplaceAll(firstLine(text), "*", ""),
+			"text":     text,
+		}
+	case "discord":
+		payload = map[string]string{"content": text}
+	case "telegram":
+		payload = map[string]string{"chat_id": chatID, "text": text}
+	}
+	return json.Marshal(payload)
+}
+
+func truncate(text string, limit int) string {
+	if limit == 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codes op de Debezium par opération.
+var debeziumOps = map[string]string{
+	"INSERT":   "c",
+	"UPDATE":   "u",
+	"DELETE":   "d",
+	OpSnapshot: "r",
+}
+
+// debeziumEnvelope reprend la charge utile d'un message Debezium (convertisseur
+// JSON sans schéma).
+type debeziumEnvelope struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source debeziumSource         `json:"source"`
+	Op     string                 `json:"op"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+type debeziumSource struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Schema    string `json:"schema,omitempty"`
+	Table     string `json:"table"`
+	// LSN PostgreSQL; pos porte l'id de la ligne d'audit MySQL ou SQLite;
+	// change_lsn le LSN SQL Server.
+	LSN       *int64 `json:"lsn,omitempty"`
+	Pos       *int64 `json:"pos,omitempty"`
+	ChangeLSN string `json:"change_lsn,omitempty"`
+}
+
+func encodeDebezium(event *ChangeEvent, headers http.Header) ([]byte, http.Header, error) {
+	op, ok := debeziumOps[event.Operation]
+	if !ok {
+		// SCHEMA_CHANGE n'a pas d'équivalent dans le flux de données Debezium
+		return nil, headers, nil
+	}
+
+	env := debeziumEnvelope{
+		Op:     op,
+		TsMs:   time.Now().UnixMilli(),
+		Source: newDebeziumSource(event),
+	}
+	switch op {
+	case "u":
+		env.Before, env.After = event.OldData, event.Data
+	case "d":
+		// Pour un DELETE, data contient la ligne supprimée
+		env.Before = event.Data
+	default:
+		env.After = event.Data
+	}
+
+	headers.Set("Content-Type", "application/json")
+	body, err := json.Marshal(&env)
+	return body, headers, err
+}
+
+func newDebeziumSource(event *ChangeEvent) debeziumSource {
+	src := debeziumSource{
+		Version:  "paypayo",
+		Name:     "paypayo",
+		TsMs:     event.Timestamp.UnixMilli(),
+		Snapshot: strconv.FormatBool(event.Operation == OpSnapshot),
+		Table:    event.Table,
+	}
+	if event.Source == nil {
+		return src
+	}
+
+	src.Connector = event.Source.Connector
+	src.Name = event.Source.Database
+	src.DB = event.Source.Database
+	src.Schema = event.Source.Schema
+	src.Table = event.Source.Table
+	if event.Source.Position == "" {
+		return src
+	}
+	switch event.Source.Connector {
+	case "postgres":
+		if lsn, ok := parseLSN(event.Source.Position); ok {
+			src.LSN = &lsn
+		}
+	case "sqlserver":
+		src.ChangeLSN = event.Source.Position
+	default:
+		if pos, err := strconv.ParseInt(event.Source.Position, 10, 64); err == nil {
+			src.Pos = &pos
+		}
+	}
+	return src
+}
+
+// parseLSN convertit un LSN PostgreSQL ("16/B374D848") en entier, comme
+// Debezium.
+func parseLSN(s string) (int64, bool) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, false
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int64(h<<32 | l), true
+}
@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/render"
+)
+
+// destination est un récepteur de webhook.destinations, avec ses templates
+// compilés. Sans template de corps, l'événement est envoyé au format
+// webhook.format; les messageries reçoivent le texte de message.
+type destination struct {
+	name        string
+	kind        string
+	url         string
+	path        *template.Template
+	query       *template.Template
+	headers     map[string]*template.Template
+	body        *template.Template
+	contentType string
+	message     *template.Template
+	chatID      string
+	operations  map[string]bool
+	when        *template.Template
+}
+
+// newDestinations compile les destinations; sans destination configurée,
+// les événements vont à webhook.url.
+func newDestinations(cfg *config.WebhookConfig) ([]*destination, error) {
+	if len(cfg.Destinations) == 0 {
+		// Sans webhook.url, les événements ne vont qu'aux abonnés gRPC
+		if cfg.URL == "" {
+			return nil, nil
+		}
+		return []*destination{{url: cfg.URL}}, nil
+	}
+
+	var dests []*destination
+	for i, dc := range cfg.Destinations {
+		d := &destination{
+			name:        dc.Name,
+			kind:        dc.Type,
+			url:         dc.URL,
+			headers:     make(map[string]*template.Template, len(dc.Headers)),
+			contentType: dc.ContentType,
+			chatID:      dc.ChatID,
+		}
+		if d.name == "" {
+			d.name = i18n.Sprintf("destination %d", i+1)
+		}
+		if d.kind == "" {
+			d.kind = "webhook"
+		}
+		if d.kind == "telegram" {
+			base := d.url
+			if base == "" {
+				base = telegramAPI
+			}
+			d.url = strings.TrimSuffix(base, "/") + "/bot" + dc.Token + "/sendMessage"
+		}
+		if d.url == "" {
+			d.url = cfg.URL
+		}
+		if d.contentType == "" {
+			d.contentType = "application/json"
+		}
+		if len(dc.Operations) > 0 {
+			d.operations = make(map[string]bool, len(dc.Operations))
+			for _, op := range dc.Operations {
+				d.operations[strings.ToUpper(op)] = true
+			}
+		}
+
+		var err error
+		if d.path, err = parseOptional("path", dc.Path); err != nil {
+			return nil, i18n.Errorf("%s: %w", d.name, err)
+		}
+		if d.query, err = parseOptional("query", dc.Query); err != nil {
+			return nil, i18n.Errorf("%s: %w", d.name, err)
+		}
+		if d.body, err = parseOptional("body", dc.Body); err != nil {
+			return nil, i18n.Errorf("%s: %w", d.name, err)
+		}
+		if d.when, err = parseOptional("when", dc.When); err != nil {
+			return nil, i18n.Errorf("%s: %w", d.name, err)
+		}
+		if dc.IsChat() {
+			message := dc.Message
+			if message == "" {
+				message = chatPreset(d.kind)
+			}
+			if d.message, err = render.Parse("message", message); err != nil {
+				return nil, i18n.Errorf("%s: %w", d.name, err)
+			}
+		}
+		for name, value := range dc.Headers {
+			if d.headers[name], err = render.Parse("headers."+name, value); err != nil {
+				return nil, i18n.Errorf("%s: %w", d.name, err)
+			}
+		}
+		dests = append(dests, d)
+	}
+	return dests, nil
+}
+
+// label complète les messages de log avec le nom de la destination.
+func (d *destination) label() string {
+	if d.name == "" {
+		return ""
+	}
+	return " (" + d.name + ")"
+}
+
+func parseOptional(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return render.Parse(name, text)
+}
+
+// newView prépare la vue de l'événement passée aux templates.
+func newView(event *ChangeEvent) *render.Event {
+	return &render.Event{
+		ID:        event.ID,
+		Operation: event.Operation,
+		Table:     event.Table,
+		Timestamp: event.Timestamp,
+		Data:      event.Data,
+		OldData:   event.OldData,
+		Changed:   render.Changed(event.Data, event.OldData),
+	}
+}
+
+// accepts applique les filtres de la destination: opérations et condition
+// when.
+func (d *destination) accepts(view *render.Event) (bool, error) {
+	if d.operations != nil && !d.operations[view.Operation] {
+		return false, nil
+	}
+	if d.when == nil {
+		return true, nil
+	}
+	out, err := render.Execute(d.when, view)
+	if err != nil {
+		return false, i18n.Errorf("erreur template when: %w", err)
+	}
+	return strings.TrimSpace(out) == "true", nil
+}
+
+// request construit l'URL, le corps et les en-têtes envoyés à la
+// destination. Un corps nil signifie que l'événement n'est pas envoyé.
+func (d *destination) request(event *ChangeEvent, view *render.Event, cfg *config.WebhookConfig) (string, []byte, http.Header, error) {
+	target, err := d.target(view)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var body []byte
+	var headers http.Header
+	switch {
+	case d.message != nil:
+		text, err := render.Execute(d.message, view)
+		if err != nil {
+			return "", nil, nil, i18n.Errorf("erreur template message: %w", err)
+		}
+		if body, err = chatBody(d.kind, text, d.chatID); err != nil {
+			return "", nil, nil, err
+		}
+		headers = http.Header{}
+		headers.Set("Content-Type", "application/json")
+	case d.body == nil:
+		if body, headers, err = encodePayload(event, cfg); err != nil {
+			return "", nil, nil, i18n.Errorf("erreur marshalling JSON: %w", err)
+		}
+	default:
+		text, err := render.Execute(d.body, view)
+		if err != nil {
+			return "", nil, nil, i18n.Errorf("erreur template body: %w", err)
+		}
+		body = []byte(text)
+		headers = http.Header{}
+		headers.Set("Content-Type", d.contentType)
+	}
+
+	for name, tmpl := range d.headers {
+		value, err := render.Execute(tmpl, view)
+		if err != nil {
+			return "", nil, nil, i18n.Errorf("erreur template en-tête %s: %w", name, err)
+		}
+		headers.Set(name, value)
+	}
+	return target, body, headers, nil
+}
+
+// target ajoute à l'URL de la destination le chemin et la query string
+// produits par les templates.
+func (d *destination) target(view *render.Event) (string, error) {
+	if d.path == nil && d.query == nil {
+		return d.url, nil
+	}
+
+	u, err := url.Parse(d.url)
+	if err != nil {
+		return "", err
+	}
+	if d.path != nil {
+		path, err := render.Execute(d.path, view)
+		if err != nil {
+			return "", i18n.Errorf("erreur template path: %w", err)
+		}
+		u = u.JoinPath(path)
+	}
+	if d.query != nil {
+		raw, err := render.Execute(d.query, view)
+		if err != nil {
+			return "", i18n.Errorf("erreur template query: %w", err)
+		}
+		extra, err := url.ParseQuery(raw)
+		if err != nil {
+			return "", i18n.Errorf("query %q invalide: %w", raw, err)
+		}
+		q := u.Query()
+		for key, values := range extra {
+			for _, v := range values {
+				q.Add(key, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"app-db-listener/internal/config"
+)
+
+// Types CloudEvents par opération.
+var cloudEventTypes = map[string]string{
+	"INSERT":       "com.paypayo.row.inserted",
+	"UPDATE":       "com.paypayo.row.updated",
+	"DELETE":       "com.paypayo.row.deleted",
+	OpSnapshot:     "com.paypayo.row.snapshot",
+	OpSchemaChange: "com.paypayo.schema.changed",
+}
+
+// NewID renvoie un identifiant d'événement aléatoire (UUID v4).
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// encodePayload construit le corps et les en-têtes de la requête selon
+// webhook.format. Un corps nil signifie que l'événement n'a pas d'équivalent
+// dans ce format et n'est pas envoyé.
+func encodePayload(event *ChangeEvent, cfg *config.WebhookConfig) ([]byte, http.Header, error) {
+	headers := http.Header{}
+
+	switch cfg.Format {
+	case "cloudevents":
+		return encodeCloudEvent(event, cfg, headers)
+	case "debezium":
+		return encodeDebezium(event, headers)
+	default:
+		// Le format json garde la forme historique de l'événement
+		native := *event
+		native.ID = ""
+		native.Source = nil
+		if !cfg.IncludeSchema {
+			native.Schema = nil
+		}
+		headers.Set("Content-Type", "application/json")
+		body, err := json.Marshal(&native)
+		return body, headers, err
+	}
+}
+
+// cloudEvent est l'enveloppe CloudEvents 1.0 en mode structured.
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+func encodeCloudEvent(event *ChangeEvent, cfg *config.WebhookConfig, headers http.Header) ([]byte, http.Header, error) {
+	ce := cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              event.ID,
+		Source:          cloudEventSource(event, cfg),
+		Type:            cloudEventType(event.Operation),
+		Subject:         event.Table,
+		Time:            event.Timestamp.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            event.Data,
+	}
+	if ce.ID == "" {
+		ce.ID = NewID()
+	}
+
+	if cfg.CloudEventsMode == "binary" {
+		headers.Set("Content-Type", ce.DataContentType)
+		headers.Set("Ce-Specversion", ce.SpecVersion)
+		headers.Set("Ce-Id", ce.ID)
+		headers.Set("Ce-Source", ce.Source)
+		headers.Set("Ce-Type", ce.Type)
+		headers.Set("Ce-Subject", ce.Subject)
+		headers.Set("Ce-Time", ce.Time)
+		body, err := json.Marshal(ce.Data)
+		return body, headers, err
+	}
+
+	headers.Set("Content-Type", "application/cloudevents+json")
+	body, err := json.Marshal(&ce)
+	return body, headers, err
+}
+
+func cloudEventType(operation string) string {
+	if t, ok := cloudEventTypes[operation]; ok {
+		return t
+	}
+	return "com.paypayo." + operation
+}
+
+func cloudEventSource(event *ChangeEvent, cfg *config.WebhookConfig) string {
+	if cfg.CloudEventsSource != "" {
+		return cfg.CloudEventsSource
+	}
+	if event.Source == nil {
+		return "/paypayo"
+	}
+	return fmt.Sprintf("/paypayo/%s/%s", event.Source.Connector, event.Source.Database)
+}
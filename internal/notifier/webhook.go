@@ -2,79 +2,233 @@ package notifier
 
 import (
 	"bytes"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
 	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/render"
+)
+
+const (
+	// OpSnapshot marque une ligne existante lue pendant un snapshot.
+	OpSnapshot = "SNAPSHOT"
+	// OpWatermark délimite une fenêtre de lecture de snapshot dans le flux de
+	// changements; ces événements ne sont jamais envoyés.
+	OpWatermark = "WATERMARK"
+	// OpSchemaChange signale une modification des colonnes de la table
+	// surveillée.
+	OpSchemaChange = "SCHEMA_CHANGE"
 )
 
 type ChangeEvent struct {
-	Operation string                 `json:"operation"` // insert, update, delete
+	// ID identifie l'événement de façon unique (attribut id des CloudEvents).
+	ID        string                 `json:"id,omitempty"`
+	Operation string                 `json:"operation"` // insert, update, delete, snapshot
 	Table     string                 `json:"table"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
 	OldData   map[string]interface{} `json:"old_data,omitempty"` // Pour les updates
+	// Schema décrit le type des colonnes; envoyé si webhook.include_schema.
+	Schema []Field `json:"schema,omitempty"`
+	// Source décrit l'origine de l'événement; utilisée par les formats autres
+	// que json et conservée dans la file sur disque.
+	Source *Source `json:"source,omitempty"`
+}
+
+// Source décrit la base et la table d'où provient un événement.
+type Source struct {
+	Connector string `json:"connector"` // postgres, mysql, sqlite, sqlserver
+	Database  string `json:"db"`
+	Schema    string `json:"schema,omitempty"`
+	Table     string `json:"table"`
+	// Position du changement: LSN PostgreSQL ou SQL Server, id de la ligne
+	// d'audit MySQL ou SQLite.
+	Position string `json:"position,omitempty"`
+}
+
+// Field décrit une colonne de la table: type logique (string, integer,
+// decimal, float, boolean, binary, timestamp, date, time, json, array) et type
+// natif de la base.
+type Field struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Items  string `json:"items,omitempty"` // type des éléments d'un tableau
+	DBType string `json:"db_type"`
 }
 
 type Notifier struct {
-	config *config.WebhookConfig
+	state  atomic.Pointer[notifierState]
 	logger *logger.Logger
-	client *http.Client
+	sinks  []Sink
+}
+
+// Sink reçoit chaque événement en plus des destinations HTTP (flux gRPC,
+// mode push).
+type Sink interface {
+	Publish(event *ChangeEvent) error
 }
 
-func New(cfg *config.WebhookConfig, log *logger.Logger) *Notifier {
-	return &Notifier{
+// notifierState est remplacé en bloc lors d'un rechargement de la
+// configuration; un envoi en cours garde l'état avec lequel il a commencé.
+type notifierState struct {
+	config       *config.WebhookConfig
+	client       *http.Client
+	destinations []*destination
+}
+
+func New(cfg *config.WebhookConfig, log *logger.Logger) (*Notifier, error) {
+	n := &Notifier{logger: log}
+	if err := n.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Reload applique une nouvelle configuration webhook (URL, timeout, retries,
+// destinations) aux prochains envois.
+func (n *Notifier) Reload(cfg *config.WebhookConfig) error {
+	dests, err := newDestinations(cfg)
+	if err != nil {
+		return i18n.Errorf("erreur compilation des templates: %w", err)
+	}
+
+	n.state.Store(&notifierState{
 		config: cfg,
-		logger: log,
 		client: &http.Client{
 			Timeout: time.Duration(cfg.Timeout) * time.Second,
 		},
+		destinations: dests,
+	})
+	return nil
+}
+
+// AddSink ajoute un récepteur; à appeler avant le démarrage du listener.
+func (n *Notifier) AddSink(sink Sink) {
+	n.sinks = append(n.sinks, sink)
+}
+
+// Notify envoie l'événement en parallèle à chaque destination et à chaque
+// récepteur: les tentatives de l'un ne retardent pas les autres. Les erreurs
+// de tous ceux en échec sont renvoyées ensemble. Les messages d'envoi sont
+// écrits dans log (celui du notifier si nil), complété des champs de
+// l'événement.
+func (n *Notifier) Notify(event *ChangeEvent, log *logger.Logger) error {
+	state := n.state.Load()
+	view := newView(event)
+	if log == nil {
+		log = n.logger
+	}
+	log = log.With(logger.FieldTable, event.Table, logger.FieldOperation, event.Operation, logger.FieldEventID, event.ID)
+
+	errs := make([]error, len(state.destinations)+len(n.sinks))
+	var wg sync.WaitGroup
+	for i, dest := range state.destinations {
+		wg.Add(1)
+		go func(i int, dest *destination) {
+			defer wg.Done()
+			if err := n.deliver(state, dest, event, view, log); err != nil {
+				if dest.name != "" {
+					err = i18n.Errorf("%s: %w", dest.name, err)
+				}
+				errs[i] = err
+			}
+		}(i, dest)
+	}
+	for i, sink := range n.sinks {
+		wg.Add(1)
+		go func(i int, sink Sink) {
+			defer wg.Done()
+			errs[i] = sink.Publish(event)
+		}(len(state.destinations)+i, sink)
 	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func (n *Notifier) Notify(event *ChangeEvent) error {
-	jsonData, err := json.Marshal(event)
+func (n *Notifier) deliver(state *notifierState, dest *destination, event *ChangeEvent, view *render.Event, log *logger.Logger) error {
+	cfg := state.config
+
+	ok, err := dest.accepts(view)
+	if err != nil {
+		metrics.EventsFailed.Add(1)
+		return err
+	}
+	if !ok {
+		log.Debug("Événement %s filtré%s", event.Operation, dest.label())
+		return nil
+	}
+
+	target, body, headers, err := dest.request(event, view, cfg)
 	if err != nil {
-		return fmt.Errorf("erreur marshalling JSON: %w", err)
+		metrics.EventsFailed.Add(1)
+		return err
+	}
+	if body == nil {
+		log.Debug("Événement %s sans équivalent au format %s, non envoyé", event.Operation, cfg.Format)
+		return nil
 	}
 
 	var lastErr error
-	for attempt := 0; attempt <= n.config.RetryCount; attempt++ {
+	for attempt := 0; attempt <= cfg.RetryCount; attempt++ {
+		alog := log.With(logger.FieldAttempt, attempt+1)
 		if attempt > 0 {
-			n.logger.Info("Tentative %d/%d pour l'événement %s", attempt, n.config.RetryCount, event.Operation)
-			time.Sleep(time.Duration(n.config.RetryDelay) * time.Second)
+			alog.Info("Tentative %d/%d pour l'événement %s", attempt, cfg.RetryCount, event.Operation)
+			time.Sleep(time.Duration(cfg.RetryDelay) * time.Second)
 		}
 
-		req, err := http.NewRequest("POST", n.config.URL, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequest("POST", target, bytes.NewBuffer(body))
 		if err != nil {
-			lastErr = fmt.Errorf("erreur création requête: %w", err)
-			n.logger.Error("Erreur création requête: %v", err)
+			err = redact(err)
+			lastErr = i18n.Errorf("erreur création requête: %w", err)
+			alog.Error("Erreur création requête%s: %v", dest.label(), err)
 			continue
 		}
 
-		req.Header.Set("Content-Type", "application/json")
+		for name, values := range headers {
+			req.Header[name] = values
+		}
 
-		resp, err := n.client.Do(req)
+		start := time.Now()
+		resp, err := state.client.Do(req)
+		alog = alog.With(logger.FieldDuration, time.Since(start).Milliseconds())
 		if err != nil {
-			lastErr = fmt.Errorf("erreur envoi requête: %w", err)
-			n.logger.Error("Erreur envoi webhook (tentative %d): %v", attempt+1, err)
+			err = redact(err)
+			lastErr = i18n.Errorf("erreur envoi requête: %w", err)
+			alog.Error("Erreur envoi webhook (tentative %d)%s: %v", attempt+1, dest.label(), err)
 			continue
 		}
 
 		resp.Body.Close()
+		alog = alog.With(logger.FieldStatusCode, resp.StatusCode)
 
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			n.logger.Info("Notification envoyée avec succès: %s sur table %s", event.Operation, event.Table)
+			alog.Info("Notification envoyée avec succès: %s sur table %s%s", event.Operation, event.Table, dest.label())
+			metrics.EventsDelivered.Add(1)
 			return nil
 		}
 
-		lastErr = fmt.Errorf("statut HTTP %d", resp.StatusCode)
-		n.logger.Warn("Webhook retourné statut %d (tentative %d)", resp.StatusCode, attempt+1)
+		lastErr = i18n.Errorf("statut HTTP %d", resp.StatusCode)
+		alog.Warn("Webhook retourné statut %d (tentative %d)%s", resp.StatusCode, attempt+1, dest.label())
 	}
 
-	n.logger.Error("Échec notification après %d tentatives: %v", n.config.RetryCount+1, lastErr)
+	log.With(logger.FieldAttempt, cfg.RetryCount+1).Error("Échec notification après %d tentatives%s: %v", cfg.RetryCount+1, dest.label(), lastErr)
+	metrics.EventsFailed.Add(1)
 	return lastErr
 }
+
+// redact retire l'URL des erreurs du client HTTP: elle peut contenir un secret
+// (jeton du bot Telegram dans le chemin, clé en query string).
+func redact(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
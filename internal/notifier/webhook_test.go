@@ -0,0 +1,303 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+)
+
+// received est une requête reçue par le serveur de test.
+type received struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+func newServer(t *testing.T, status int) (*httptest.Server, <-chan received) {
+	t.Helper()
+	reqs := make(chan received, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- received{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs
+}
+
+func newTestLogger(t *testing.T) (*logger.Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	log, err := logger.New(&config.LoggingConfig{File: path, Level: "debug", Outputs: []string{"file"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log, path
+}
+
+func newTestNotifier(t *testing.T, dests ...config.DestinationConfig) (*Notifier, string) {
+	t.Helper()
+	log, path := newTestLogger(t)
+	n, err := New(&config.WebhookConfig{Timeout: 5, Format: "json", Destinations: dests}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, path
+}
+
+func updateEvent() *ChangeEvent {
+	return &ChangeEvent{
+		ID:        "evt-1",
+		Operation: "UPDATE",
+		Table:     "public.users",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:      map[string]interface{}{"id": float64(7), "name": "Bob"},
+		OldData:   map[string]interface{}{"id": float64(7), "name": "Alice"},
+	}
+}
+
+func receive(t *testing.T, reqs <-chan received) received {
+	t.Helper()
+	select {
+	case r := <-reqs:
+		return r
+	default:
+		t.Fatal("aucune requête reçue")
+		return received{}
+	}
+}
+
+func decodeBody(t *testing.T, body []byte) map[string]interface{} {
+	t.Helper()
+	var out map[string]interface{}
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("corps JSON invalide %q: %v", body, err)
+	}
+	return out
+}
+
+func TestChatBodies(t *testing.T) {
+	tests := []struct {
+		kind  string
+		field string // champ portant le texte
+		title string // première ligne du message
+	}{
+		{"slack", "text", "*UPDATE* sur public.users"},
+		{"teams", "text", "**UPDATE** sur public.users"},
+		{"discord", "content", "**UPDATE** sur public.users"},
+		{"telegram", "text", "UPDATE sur public.users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			srv, reqs := newServer(t, http.StatusOK)
+			n, _ := newTestNotifier(t, config.DestinationConfig{
+				Type: tt.kind, URL: srv.URL, Token: "123:abc", ChatID: "42",
+			})
+
+			if err := n.Notify(updateEvent(), nil); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+
+			r := receive(t, reqs)
+			if ct := r.header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			body := decodeBody(t, r.body)
+			text, _ := body[tt.field].(string)
+			if !strings.HasPrefix(text, tt.title) {
+				t.Errorf("%s = %q, attendu le préfixe %q", tt.field, text, tt.title)
+			}
+			if !strings.Contains(text, "name: Alice → Bob") {
+				t.Errorf("%s = %q, changement de name absent", tt.field, text)
+			}
+
+			switch tt.kind {
+			case "teams":
+				if body["@type"] != "MessageCard" || body["summary"] != "UPDATE sur public.users" {
+					t.Errorf("carte Teams inattendue: %v", body)
+				}
+				if !strings.Contains(text, "\n\n") {
+					t.Errorf("text = %q, retours à la ligne non doublés", text)
+				}
+			case "telegram":
+				if r.path != "/bot123:abc/sendMessage" {
+					t.Errorf("chemin = %q", r.path)
+				}
+				if body["chat_id"] != "42" {
+					t.Errorf("chat_id = %v", body["chat_id"])
+				}
+			}
+		})
+	}
+}
+
+// Le message par défaut suit la langue choisie à la compilation des
+// destinations.
+func TestChatPresetLanguage(t *testing.T) {
+	i18n.SetLanguage(i18n.English)
+	defer i18n.SetLanguage(i18n.French)
+
+	srv, reqs := newServer(t, http.StatusOK)
+	n, _ := newTestNotifier(t, config.DestinationConfig{Type: "slack", URL: srv.URL})
+
+	if err := n.Notify(updateEvent(), nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	text, _ := decodeBody(t, receive(t, reqs).body)["text"].(string)
+	if !strings.HasPrefix(text, "*UPDATE* on public.users") {
+		t.Errorf("text = %q, message anglais attendu", text)
+	}
+}
+
+func TestChatMessageTruncated(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusOK)
+	n, _ := newTestNotifier(t, config.DestinationConfig{
+		Type: "discord", URL: srv.URL, Message: strings.Repeat("é", 3000),
+	})
+
+	if err := n.Notify(updateEvent(), nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	text, _ := decodeBody(t, receive(t, reqs).body)["content"].(string)
+	if got := len([]rune(text)); got != chatLimits["discord"] {
+		t.Errorf("message de %d caractères, attendu %d", got, chatLimits["discord"])
+	}
+	if !strings.HasSuffix(text, "…") {
+		t.Errorf("message tronqué sans …")
+	}
+}
+
+func TestTemplateBody(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusOK)
+	n, _ := newTestNotifier(t, config.DestinationConfig{
+		URL:         srv.URL,
+		Path:        "/users/{{.Data.id}}",
+		Query:       "op={{.Operation}}",
+		Headers:     map[string]string{"X-Table": "{{.Table}}"},
+		Body:        `<user id="{{.Data.id}}">{{.Data.name}}</user>`,
+		ContentType: "application/xml",
+	})
+
+	if err := n.Notify(updateEvent(), nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	r := receive(t, reqs)
+	if r.method != http.MethodPost {
+		t.Errorf("méthode = %s", r.method)
+	}
+	if r.path != "/users/7" || r.query != "op=UPDATE" {
+		t.Errorf("URL = %s?%s", r.path, r.query)
+	}
+	if got := r.header.Get("X-Table"); got != "public.users" {
+		t.Errorf("X-Table = %q", got)
+	}
+	if got := r.header.Get("Content-Type"); got != "application/xml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got, want := string(r.body), `<user id="7">Bob</user>`; got != want {
+		t.Errorf("corps = %s, attendu %s", got, want)
+	}
+}
+
+func TestDestinationFilters(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusOK)
+	n, _ := newTestNotifier(t,
+		config.DestinationConfig{Name: "inserts", URL: srv.URL, Operations: []string{"insert"}},
+		config.DestinationConfig{Name: "bob", URL: srv.URL, Path: "/bob", When: `{{eq .Data.name "Bob"}}`},
+	)
+
+	if err := n.Notify(updateEvent(), nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if r := receive(t, reqs); r.path != "/bob" {
+		t.Errorf("requête reçue sur %s", r.path)
+	}
+	if len(reqs) != 0 {
+		t.Errorf("%d requête(s) en trop", len(reqs))
+	}
+}
+
+// Le jeton Telegram fait partie du chemin de l'URL: il ne doit apparaître ni
+// dans l'erreur renvoyée ni dans les logs.
+func TestTelegramTokenRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	const token = "123456:SECRET-TOKEN"
+	n, logPath := newTestNotifier(t, config.DestinationConfig{
+		Name: "alertes", Type: "telegram", URL: srv.URL, Token: token, ChatID: "42",
+	})
+
+	err := n.Notify(updateEvent(), nil)
+	if err == nil {
+		t.Fatal("Notify: erreur attendue, serveur fermé")
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("jeton présent dans l'erreur: %v", err)
+	}
+
+	logs, rerr := os.ReadFile(logPath)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if strings.Contains(string(logs), "SECRET") {
+		t.Errorf("jeton présent dans les logs:\n%s", logs)
+	}
+	if !strings.Contains(string(logs), "alertes") {
+		t.Errorf("nom de la destination absent des logs:\n%s", logs)
+	}
+}
+
+// sinkFunc adapte une fonction à l'interface Sink.
+type sinkFunc func(event *ChangeEvent) error
+
+func (f sinkFunc) Publish(event *ChangeEvent) error { return f(event) }
+
+// Un récepteur reçoit l'événement sans attendre les nouvelles tentatives
+// d'une destination en échec.
+func TestSinkNotDelayedByRetries(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusServiceUnavailable)
+	log, _ := newTestLogger(t)
+	n, err := New(&config.WebhookConfig{
+		Timeout: 5, RetryCount: 1, RetryDelay: 1, Format: "json",
+		Destinations: []config.DestinationConfig{{Name: "lente", URL: srv.URL}},
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	published := make(chan time.Duration, 1)
+	n.AddSink(sinkFunc(func(*ChangeEvent) error {
+		published <- time.Since(start)
+		return nil
+	}))
+
+	err = n.Notify(updateEvent(), nil)
+	if err == nil || !strings.Contains(err.Error(), "lente") {
+		t.Errorf("Notify = %v, erreur de la destination attendue", err)
+	}
+	if len(reqs) != 2 {
+		t.Errorf("%d requête(s), attendu 2", len(reqs))
+	}
+	if d := <-published; d > 500*time.Millisecond {
+		t.Errorf("récepteur appelé après %s", d)
+	}
+}
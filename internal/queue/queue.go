@@ -0,0 +1,182 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+)
+
+const (
+	PolicyBlock = "block"
+	PolicySpill = "spill_to_disk"
+	PolicyDrop  = "drop"
+
+	defaultSize = 100
+)
+
+var ErrDropped = i18n.NewError("file d'événements pleine, événement perdu")
+
+// Queue fait le lien entre les listeners et les workers. Quand la file est
+// pleine, le comportement dépend de la politique configurée.
+type Queue struct {
+	ch     chan *notifier.ChangeEvent
+	policy string
+	spill  *spillFile
+	logger *logger.Logger
+	done   chan struct{}
+	once   sync.Once
+}
+
+func New(cfg *config.WorkerConfig, log *logger.Logger) (*Queue, error) {
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = defaultSize
+	}
+
+	policy := cfg.QueuePolicy
+	if policy == "" {
+		policy = PolicyBlock
+	}
+
+	q := &Queue{
+		ch:     make(chan *notifier.ChangeEvent, size),
+		policy: policy,
+		logger: log,
+		done:   make(chan struct{}),
+	}
+
+	switch policy {
+	case PolicyBlock, PolicyDrop:
+	case PolicySpill:
+		spill, err := openSpillFile(cfg.SpillDir)
+		if err != nil {
+			return nil, err
+		}
+		q.spill = spill
+		if spill.truncated > 0 {
+			metrics.SpillLost.Add(1)
+			log.Warn("Fichier de débordement: dernière ligne incomplète (%d octets) ignorée", spill.truncated)
+		}
+		go q.drain()
+	default:
+		return nil, i18n.Errorf("politique de file inconnue: %s", policy)
+	}
+
+	metrics.QueuePolicy.Set(policy)
+	metrics.SetQueueLength(q.Len)
+
+	return q, nil
+}
+
+func (q *Queue) Policy() string {
+	return q.policy
+}
+
+// Len renvoie le nombre d'événements en attente, en mémoire et sur disque.
+func (q *Queue) Len() int64 {
+	n := int64(len(q.ch))
+	if q.spill != nil {
+		n += int64(q.spill.len())
+	}
+	return n
+}
+
+// C renvoie le canal consommé par les workers.
+func (q *Queue) C() <-chan *notifier.ChangeEvent {
+	return q.ch
+}
+
+// Push ajoute un événement à la file. Avec la politique "drop", ErrDropped est
+// renvoyée si la file est pleine; avec "block", l'appel attend qu'une place se
+// libère ou que le contexte soit annulé.
+func (q *Queue) Push(ctx context.Context, event *notifier.ChangeEvent) error {
+	metrics.EventsReceived.Add(1)
+
+	switch q.policy {
+	case PolicyDrop:
+		select {
+		case q.ch <- event:
+		default:
+			metrics.EventsDropped.Add(1)
+			return ErrDropped
+		}
+	case PolicySpill:
+		spilled, err := q.spill.pushOrSpill(q.ch, event)
+		if err != nil {
+			return err
+		}
+		if spilled {
+			metrics.EventsSpilled.Add(1)
+			return nil
+		}
+	default:
+		select {
+		case q.ch <- event:
+		default:
+			metrics.QueueBlocked.Add(1)
+			select {
+			case q.ch <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	metrics.EventsQueued.Add(1)
+	return nil
+}
+
+// drain réinjecte dans la file, dans l'ordre, les événements écrits sur disque.
+// Un événement non remis avant Close reste sur disque pour le prochain
+// démarrage.
+func (q *Queue) drain() {
+	for {
+		event, err := q.spill.next()
+		if errors.Is(err, errSpillClosed) {
+			return
+		}
+		var lost *lostEventError
+		if errors.As(err, &lost) {
+			metrics.SpillLost.Add(1)
+			q.logger.Error("Événement illisible dans le fichier de débordement, ignoré: %v", err)
+			continue
+		}
+		if err != nil {
+			// Le fichier est conservé: la lecture reprend au même événement.
+			q.logger.Error("Erreur lecture du fichier de débordement: %v", err)
+			select {
+			case <-q.done:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		select {
+		case q.ch <- event:
+		case <-q.done:
+			return
+		}
+		q.spill.ack()
+
+		metrics.EventsQueued.Add(1)
+	}
+}
+
+func (q *Queue) Close() error {
+	var err error
+	q.once.Do(func() {
+		close(q.done)
+		if q.spill != nil {
+			err = q.spill.close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+)
+
+func newTestQueue(t *testing.T, policy string, size int, dir string) *Queue {
+	t.Helper()
+	log, err := logger.New(&config.LoggingConfig{File: filepath.Join(t.TempDir(), "test.log"), Level: "debug", Outputs: []string{"file"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := New(&config.WorkerConfig{QueueSize: size, QueuePolicy: policy, SpillDir: dir}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func event(id int) *notifier.ChangeEvent {
+	return &notifier.ChangeEvent{
+		ID:        strconv.Itoa(id),
+		Operation: "insert",
+		Table:     "users",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:      map[string]interface{}{"id": id},
+	}
+}
+
+func push(t *testing.T, q *Queue, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		if err := q.Push(context.Background(), event(id)); err != nil {
+			t.Fatalf("Push(%d): %v", id, err)
+		}
+	}
+}
+
+// expect lit les événements de la file et vérifie leur ordre.
+func expect(t *testing.T, q *Queue, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		select {
+		case ev := <-q.C():
+			if ev.ID != strconv.Itoa(id) {
+				t.Fatalf("événement %s reçu, %d attendu", ev.ID, id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("événement %d non reçu", id)
+		}
+	}
+}
+
+// waitPending attend que le nombre d'événements sur disque atteigne n.
+func waitPending(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.spill.len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d événements sur disque, %d attendus", q.spill.len(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	q := newTestQueue(t, PolicyDrop, 1, "")
+	push(t, q, 1)
+	if err := q.Push(context.Background(), event(2)); !errors.Is(err, ErrDropped) {
+		t.Fatalf("Push sur file pleine = %v, attendu ErrDropped", err)
+	}
+	expect(t, q, 1)
+}
+
+func TestBlockCanceled(t *testing.T) {
+	q := newTestQueue(t, PolicyBlock, 1, "")
+	push(t, q, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Push(ctx, event(2)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Push bloqué = %v, attendu DeadlineExceeded", err)
+	}
+}
+
+func TestSpillOrder(t *testing.T) {
+	q := newTestQueue(t, PolicySpill, 2, t.TempDir())
+	push(t, q, 1, 2, 3, 4, 5)
+
+	if got := q.Len(); got != 5 {
+		t.Errorf("Len = %d, attendu 5", got)
+	}
+	if got := expvar.Get("queue_length").String(); got != "5" {
+		t.Errorf("queue_length = %s, attendu 5", got)
+	}
+
+	expect(t, q, 1, 2, 3, 4, 5)
+	waitPending(t, q, 0)
+
+	// Le disque est vide: l'événement suivant passe directement en mémoire.
+	push(t, q, 6)
+	if q.spill.len() != 0 {
+		t.Errorf("événement écrit sur disque alors que la file n'est pas pleine")
+	}
+	expect(t, q, 6)
+	if got := q.Len(); got != 0 {
+		t.Errorf("Len = %d, attendu 0", got)
+	}
+}
+
+func TestSpillRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	q := newTestQueue(t, PolicySpill, 2, dir)
+	push(t, q, 1, 2, 3, 4, 5)
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Les événements en mémoire sont perdus à l'arrêt, ceux sur disque sont
+	// repris dans l'ordre.
+	q = newTestQueue(t, PolicySpill, 2, dir)
+	expect(t, q, 3, 4, 5)
+	waitPending(t, q, 0)
+}
+
+func TestSpillCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	q := newTestQueue(t, PolicySpill, 1, dir)
+	push(t, q, 1, 2, 3, 4)
+	expect(t, q, 1)
+	// 2 est remis dans la file et retiré du décompte disque.
+	waitPending(t, q, 2)
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q = newTestQueue(t, PolicySpill, 4, dir)
+	expect(t, q, 3, 4)
+	waitPending(t, q, 0)
+	select {
+	case ev := <-q.C():
+		t.Fatalf("événement %s déjà remis renvoyé", ev.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSpillPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, spillFileName)
+	content := `{"id":"1","operation":"insert","table":"users","timestamp":"2024-01-02T03:04:05Z","data":{}}` + "\n" +
+		`not json` + "\n" +
+		`{"id":"2","operation":"insert","table":"users","timestamp":"2024-01-02T03:04:05Z","data":{}}` + "\n" +
+		`{"id":"3","opera`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	partial := int64(len(`{"id":"3","opera`))
+
+	// La ligne incomplète est retirée du fichier à l'ouverture.
+	s, err := openSpillFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.pending != 3 || s.truncated != partial {
+		t.Errorf("pending = %d, truncated = %d; attendu 3, %d", s.pending, s.truncated, partial)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(content))-partial {
+		t.Errorf("fichier de %d octets après ouverture, %d attendus", info.Size(), int64(len(content))-partial)
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// La ligne illisible est ignorée.
+	lost := metrics.SpillLost.Value()
+	q := newTestQueue(t, PolicySpill, 4, dir)
+	expect(t, q, 1, 2)
+	waitPending(t, q, 0)
+	if got := metrics.SpillLost.Value() - lost; got != 1 {
+		t.Errorf("spill_lost +%d, attendu +1", got)
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		in    string
+		count int
+		size  int64
+	}{
+		{"", 0, 0},
+		{"a\n", 1, 2},
+		{"a\nbc\n", 2, 5},
+		{"a\nbc", 1, 2},
+		{"abc", 0, 0},
+		{string(bytes.Repeat([]byte("x\n"), 40000)) + "y", 40000, 80000},
+	}
+
+	for _, tt := range tests {
+		count, size, total, err := countLines(bytes.NewReader([]byte(tt.in)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != tt.count || size != tt.size || total != int64(len(tt.in)) {
+			t.Errorf("countLines(%d octets) = %d, %d, %d; attendu %d, %d, %d",
+				len(tt.in), count, size, total, tt.count, tt.size, len(tt.in))
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+)
+
+const spillFileName = "paypayo-spill.jsonl"
+
+var errSpillClosed = i18n.NewError("fichier de débordement fermé")
+
+// spillFile est un journal JSONL sur disque qui reçoit les événements quand la
+// file mémoire est pleine. Tant qu'il reste des événements sur disque, les
+// nouveaux y sont aussi écrits afin de préserver l'ordre.
+type spillFile struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	w       *os.File
+	r       *os.File
+	reader  *bufio.Reader
+	pending int
+	size    int64 // octets écrits
+	off     int64 // début du prochain événement à relire
+	acked   int64 // fin du dernier événement remis dans la file
+	closed  bool
+	// Ligne incomplète (arrêt pendant une écriture) retirée à l'ouverture.
+	truncated int64
+}
+
+// lostEventError signale un événement relu du disque mais inutilisable.
+type lostEventError struct {
+	err error
+}
+
+func (e *lostEventError) Error() string {
+	return e.err.Error()
+}
+
+func (e *lostEventError) Unwrap() error {
+	return e.err
+}
+
+func openSpillFile(dir string) (*spillFile, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, i18n.Errorf("erreur création répertoire de débordement: %w", err)
+	}
+
+	path := filepath.Join(dir, spillFileName)
+
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, i18n.Errorf("erreur ouverture fichier de débordement: %w", err)
+	}
+
+	r, err := os.Open(path)
+	if err != nil {
+		w.Close()
+		return nil, i18n.Errorf("erreur ouverture fichier de débordement: %w", err)
+	}
+
+	// Les événements restés sur disque lors d'un arrêt précédent sont repris;
+	// une dernière ligne sans fin de ligne est une écriture interrompue.
+	pending, size, total, err := countLines(r)
+	if err == nil && size < total {
+		err = w.Truncate(size)
+	}
+	if err != nil {
+		w.Close()
+		r.Close()
+		return nil, i18n.Errorf("erreur lecture fichier de débordement: %w", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		w.Close()
+		r.Close()
+		return nil, err
+	}
+
+	s := &spillFile{
+		w:         w,
+		r:         r,
+		reader:    bufio.NewReader(r),
+		pending:   pending,
+		size:      size,
+		truncated: total - size,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	metrics.SpillPending.Set(int64(pending))
+
+	return s, nil
+}
+
+// countLines renvoie le nombre de lignes complètes, la taille qu'elles
+// occupent et la taille totale lue.
+func countLines(r io.Reader) (count int, size, total int64, err error) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		count += bytes.Count(buf[:n], []byte{'\n'})
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			size = total + int64(i) + 1
+		}
+		total += int64(n)
+		if err == io.EOF {
+			return count, size, total, nil
+		}
+		if err != nil {
+			return count, size, total, err
+		}
+	}
+}
+
+// pushOrSpill tente un envoi direct dans ch et n'écrit sur disque que si la
+// file est pleine ou si des événements attendent déjà sur disque.
+func (s *spillFile) pushOrSpill(ch chan *notifier.ChangeEvent, event *notifier.ChangeEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		select {
+		case ch <- event:
+			return false, nil
+		default:
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, i18n.Errorf("erreur marshalling événement: %w", err)
+	}
+
+	n, err := s.w.Write(append(data, '\n'))
+	if err != nil {
+		// Une ligne partielle décalerait la relecture des suivantes.
+		if n > 0 {
+			s.w.Truncate(s.size)
+		}
+		return false, i18n.Errorf("erreur écriture fichier de débordement: %w", err)
+	}
+
+	s.size += int64(n)
+	s.pending++
+	metrics.SpillPending.Set(int64(s.pending))
+	s.cond.Signal()
+
+	return true, nil
+}
+
+// next attend le prochain événement sur disque. L'appelant doit appeler ack
+// une fois l'événement remis dans la file.
+func (s *spillFile) next() (*notifier.ChangeEvent, error) {
+	s.mu.Lock()
+	for s.pending == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errSpillClosed
+	}
+
+	line, err := s.reader.ReadBytes('\n')
+	if err != nil {
+		// Le fichier est conservé et relu depuis le début de l'événement.
+		if _, serr := s.r.Seek(s.off, io.SeekStart); serr == nil {
+			s.reader.Reset(s.r)
+		}
+		s.mu.Unlock()
+		return nil, i18n.Errorf("erreur lecture fichier de débordement: %w", err)
+	}
+	s.off += int64(len(line))
+	s.mu.Unlock()
+
+	// UseNumber: les entiers relus du disque ne passent pas par float64.
+	var event notifier.ChangeEvent
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&event); err != nil {
+		s.ack()
+		return nil, &lostEventError{i18n.Errorf("événement illisible sur disque: %w", err)}
+	}
+
+	return &event, nil
+}
+
+func (s *spillFile) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+func (s *spillFile) ack() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending--
+	s.acked = s.off
+	if s.pending <= 0 {
+		s.truncate()
+	}
+	metrics.SpillPending.Set(int64(s.pending))
+}
+
+// truncate vide le fichier une fois tous les événements réinjectés.
+func (s *spillFile) truncate() {
+	s.pending = 0
+	s.size = 0
+	s.off = 0
+	s.acked = 0
+	s.w.Truncate(0)
+	s.r.Seek(0, io.SeekStart)
+	s.reader.Reset(s.r)
+}
+
+// close retire du fichier les événements déjà remis, pour ne pas les
+// renvoyer au prochain démarrage.
+func (s *spillFile) close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	err := s.compact()
+	s.mu.Unlock()
+
+	s.r.Close()
+	if cerr := s.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *spillFile) compact() error {
+	if s.acked == 0 {
+		return nil
+	}
+	if _, err := s.r.Seek(s.acked, io.SeekStart); err != nil {
+		return err
+	}
+	rest, err := io.ReadAll(s.r)
+	if err != nil {
+		return err
+	}
+	if err := s.w.Truncate(0); err != nil {
+		return err
+	}
+	_, err = s.w.Write(rest)
+	return err
+}
@@ -0,0 +1,122 @@
+// Package render exécute les templates utilisateur (corps, chemin, query et
+// en-têtes des webhooks) sur un événement.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"app-db-listener/internal/i18n"
+)
+
+// Funcs sont les fonctions disponibles dans les templates, en plus de celles
+// de text/template (urlquery, js, html, index, printf...).
+var Funcs = template.FuncMap{
+	"json":       toJSON,
+	"join":       strings.Join,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"pathescape": url.PathEscape,
+	"value":      FormatValue,
+}
+
+// Event est la vue d'un événement exposée aux templates.
+type Event struct {
+	ID        string
+	Operation string
+	Table     string
+	Timestamp time.Time
+	Data      map[string]interface{}
+	OldData   map[string]interface{}
+	// Changed liste les colonnes modifiées par un UPDATE.
+	Changed []string
+}
+
+// sample sert à vérifier les templates au chargement de la configuration.
+var sample = &Event{
+	ID:        "00000000-0000-4000-8000-000000000000",
+	Operation: "UPDATE",
+	Table:     "public.sample",
+	Timestamp: time.Unix(0, 0).UTC(),
+	Data:      map[string]interface{}{},
+	OldData:   map[string]interface{}{},
+	Changed:   []string{},
+}
+
+// Parse compile un template avec les fonctions de Funcs.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs).Parse(text)
+}
+
+// Check compile le template et l'exécute sur un événement fictif, pour
+// signaler dès le chargement les champs ou fonctions inconnus.
+func Check(name, text string) error {
+	tmpl, err := Parse(name, text)
+	if err != nil {
+		return err
+	}
+	_, err = Execute(tmpl, sample)
+	return err
+}
+
+// Execute applique le template à l'événement.
+func Execute(tmpl *template.Template, event *Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Changed renvoie, triées, les colonnes dont la valeur diffère entre old et
+// data.
+func Changed(data, old map[string]interface{}) []string {
+	if old == nil {
+		return nil
+	}
+
+	var cols []string
+	for name, v := range data {
+		if prev, ok := old[name]; !ok || !reflect.DeepEqual(prev, v) {
+			cols = append(cols, name)
+		}
+	}
+	for name := range old {
+		if _, ok := data[name]; !ok {
+			cols = append(cols, name)
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", i18n.Errorf("json: %w", err)
+	}
+	return string(b), nil
+}
+
+// FormatValue affiche une valeur de colonne: null, texte tel quel, JSON pour
+// les objets et tableaux.
+func FormatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return x
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(x)
+		return string(b)
+	default:
+		return fmt.Sprint(x)
+	}
+}
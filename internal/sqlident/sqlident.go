@@ -0,0 +1,227 @@
+// Package sqlident valide et échappe les identifiants et littéraux insérés
+// dans le SQL généré (triggers, fonctions, table d'audit).
+package sqlident
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"app-db-listener/internal/i18n"
+)
+
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+	SQLite
+	SQLServer
+)
+
+// MaxLength est la longueur maximale d'un identifiant, en octets (en
+// caractères pour SQL Server). SQLite n'impose pas de limite.
+func (d Dialect) MaxLength() int {
+	switch d {
+	case MySQL:
+		return 64
+	case SQLite:
+		return 1024
+	case SQLServer:
+		return 128
+	}
+	return 63
+}
+
+// Quote entoure un identifiant de guillemets (PostgreSQL, SQLite), d'accents
+// graves (MySQL) ou de crochets (SQL Server) en doublant ceux qu'il contient.
+// La casse est préservée.
+func (d Dialect) Quote(ident string) string {
+	switch d {
+	case MySQL:
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	case SQLServer:
+		return "[" + strings.ReplaceAll(ident, "]", "]]") + "]"
+	}
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// Literal renvoie une chaîne SQL littérale (Unicode pour SQL Server). Sur
+// MySQL, le sens de \ dépend de NO_BACKSLASH_ESCAPES: une chaîne qui en
+// contient devient une expression CONCAT avec CHAR(92), lue de la même façon
+// quel que soit sql_mode.
+func (d Dialect) Literal(s string) string {
+	if d == MySQL && strings.Contains(s, `\`) {
+		var parts []string
+		for i, part := range strings.Split(s, `\`) {
+			if i > 0 {
+				parts = append(parts, "CHAR(92 USING utf8mb4)")
+			}
+			if part != "" {
+				parts = append(parts, d.Literal(part))
+			}
+		}
+		return "CONCAT(" + strings.Join(parts, ", ") + ")"
+	}
+
+	s = strings.ReplaceAll(s, "'", "''")
+	if d == SQLServer {
+		return "N'" + s + "'"
+	}
+	return "'" + s + "'"
+}
+
+// Validate vérifie qu'un identifiant peut être utilisé tel quel une fois
+// échappé: non vide, UTF-8 valide, sans caractère NUL et de longueur admise.
+func (d Dialect) Validate(ident string) error {
+	switch {
+	case ident == "":
+		return i18n.Errorf("identifiant vide")
+	case !utf8.ValidString(ident):
+		return i18n.Errorf("identifiant %q: UTF-8 invalide", ident)
+	case strings.ContainsRune(ident, 0):
+		return i18n.Errorf("identifiant %q: caractère NUL interdit", ident)
+	case len(ident) > d.MaxLength():
+		return i18n.Errorf("identifiant %q: %d octets, %d maximum", ident, len(ident), d.MaxLength())
+	}
+	if d == MySQL && strings.HasSuffix(ident, " ") {
+		return i18n.Errorf("identifiant %q: espace final interdit par MySQL", ident)
+	}
+	return nil
+}
+
+// Shorten garantit qu'un identifiant dérivé (fonction, canal, trigger) tient
+// dans la limite du dialecte: au-delà, il est tronqué et suffixé d'un hash du
+// nom complet pour rester unique et stable.
+func (d Dialect) Shorten(ident string) string {
+	if len(ident) <= d.MaxLength() {
+		return ident
+	}
+
+	sum := sha256.Sum256([]byte(ident))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+
+	cut := d.MaxLength() - len(suffix)
+	for cut > 0 && !utf8.RuneStart(ident[cut]) {
+		cut--
+	}
+	return ident[:cut] + suffix
+}
+
+// Name est un nom de table éventuellement qualifié par son schéma.
+type Name struct {
+	Schema string
+	Name   string
+}
+
+// ParseName lit "table" ou "schema.table". Chaque partie peut être entourée de
+// guillemets, d'accents graves ou de crochets pour contenir un point.
+func ParseName(s string) (Name, error) {
+	var parts []string
+
+	for i := 0; ; {
+		var part string
+		if i < len(s) && (s[i] == '"' || s[i] == '`' || s[i] == '[') {
+			q := s[i]
+			if q == '[' {
+				q = ']'
+			}
+			unquoted, n, ok := unquote(s[i:], q)
+			if !ok {
+				return Name{}, i18n.Errorf("nom %q: guillemet fermant manquant", s)
+			}
+			part = unquoted
+			i += n
+		} else {
+			n := strings.IndexByte(s[i:], '.')
+			if n < 0 {
+				n = len(s) - i
+			}
+			part = s[i : i+n]
+			i += n
+		}
+
+		if part == "" {
+			return Name{}, i18n.Errorf("nom %q: partie vide", s)
+		}
+		parts = append(parts, part)
+
+		if i == len(s) {
+			break
+		}
+		if s[i] != '.' {
+			return Name{}, i18n.Errorf("nom %q: caractère inattendu après %q", s, part)
+		}
+		if i++; i == len(s) {
+			return Name{}, i18n.Errorf("nom %q: partie vide", s)
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		return Name{Name: parts[0]}, nil
+	case 2:
+		return Name{Schema: parts[0], Name: parts[1]}, nil
+	default:
+		return Name{}, i18n.Errorf("nom %q: format attendu table ou schema.table", s)
+	}
+}
+
+// unquote lit un identifiant entre guillemets et renvoie le nombre d'octets
+// consommés.
+func unquote(s string, q byte) (string, int, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			b.WriteByte(q)
+			i++
+			continue
+		}
+		return b.String(), i + 1, true
+	}
+	return "", 0, false
+}
+
+// Validate vérifie chaque partie du nom pour le dialecte donné.
+func (n Name) Validate(d Dialect) error {
+	if n.Schema != "" {
+		if err := d.Validate(n.Schema); err != nil {
+			return err
+		}
+	}
+	return d.Validate(n.Name)
+}
+
+// WithSuffix renvoie un nom dans le même schéma, suffixé (ex: "_audit").
+func (n Name) WithSuffix(suffix string) Name {
+	return Name{Schema: n.Schema, Name: n.Name + suffix}
+}
+
+func (n Name) String() string {
+	if n.Schema == "" {
+		return n.Name
+	}
+	return n.Schema + "." + n.Name
+}
+
+// QuoteName renvoie le nom qualifié échappé, ex: "billing"."payments".
+func (d Dialect) QuoteName(n Name) string {
+	if n.Schema == "" {
+		return d.Quote(n.Name)
+	}
+	return d.Quote(n.Schema) + "." + d.Quote(n.Name)
+}
+
+// QuoteList échappe une liste de colonnes séparées par des virgules.
+func (d Dialect) QuoteList(idents []string) string {
+	quoted := make([]string, len(idents))
+	for i, ident := range idents {
+		quoted[i] = d.Quote(ident)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,205 @@
+package sqlident
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Name
+		err  bool
+	}{
+		{in: "users", want: Name{Name: "users"}},
+		{in: "billing.payments", want: Name{Schema: "billing", Name: "payments"}},
+		{in: "Billing.Payments", want: Name{Schema: "Billing", Name: "Payments"}},
+		{in: `"Billing"."Payments"`, want: Name{Schema: "Billing", Name: "Payments"}},
+		{in: `"my.schema".orders`, want: Name{Schema: "my.schema", Name: "orders"}},
+		{in: "`my.db`.`order`", want: Name{Schema: "my.db", Name: "order"}},
+		{in: "[dbo].[order items]", want: Name{Schema: "dbo", Name: "order items"}},
+		{in: `"a""b"`, want: Name{Name: `a"b`}},
+		{in: "`a``b`", want: Name{Name: "a`b"}},
+		{in: "[a]]b]", want: Name{Name: "a]b"}},
+		{in: "select", want: Name{Name: "select"}},
+		{in: `"x; DROP TABLE users; --"`, want: Name{Name: "x; DROP TABLE users; --"}},
+		{in: "", err: true},
+		{in: ".users", err: true},
+		{in: "users.", err: true},
+		{in: "a..b", err: true},
+		{in: "a.b.c", err: true},
+		{in: `"users`, err: true},
+		{in: `"a"b`, err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseName(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseName(%q) = %+v, erreur attendue", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseName(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseName(%q) = %+v, attendu %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		d     Dialect
+		ident string
+		want  string
+	}{
+		{Postgres, "users", `"users"`},
+		{Postgres, "Users", `"Users"`},
+		{Postgres, "select", `"select"`},
+		{Postgres, `a"b`, `"a""b"`},
+		{Postgres, `x"; DROP TABLE users; --`, `"x""; DROP TABLE users; --"`},
+		{Postgres, "a`b'c", "\"a`b'c\""},
+		{MySQL, "users", "`users`"},
+		{MySQL, "Users", "`Users`"},
+		{MySQL, "order", "`order`"},
+		{MySQL, "a`b", "`a``b`"},
+		{MySQL, "x`; DROP TABLE users; --", "`x``; DROP TABLE users; --`"},
+		{MySQL, `a"b'c`, "`a\"b'c`"},
+		{SQLite, `a"b`, `"a""b"`},
+		{SQLServer, "a]b", "[a]]b]"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.Quote(tt.ident); got != tt.want {
+			t.Errorf("Quote(%d, %q) = %s, attendu %s", tt.d, tt.ident, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteName(t *testing.T) {
+	n := Name{Schema: "Billing", Name: `pay"ments`}
+	if got, want := Postgres.QuoteName(n), `"Billing"."pay""ments"`; got != want {
+		t.Errorf("QuoteName = %s, attendu %s", got, want)
+	}
+	if got, want := MySQL.QuoteName(Name{Name: "order"}), "`order`"; got != want {
+		t.Errorf("QuoteName = %s, attendu %s", got, want)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		d    Dialect
+		in   string
+		want string
+	}{
+		{Postgres, "users", "'users'"},
+		{Postgres, "o'brien", "'o''brien'"},
+		{Postgres, `a\b`, `'a\b'`},
+		{Postgres, "'; DROP TABLE users; --", "'''; DROP TABLE users; --'"},
+		{MySQL, "o'brien", "'o''brien'"},
+		{MySQL, `a\b`, `CONCAT('a', CHAR(92 USING utf8mb4), 'b')`},
+		{MySQL, `\`, `CONCAT(CHAR(92 USING utf8mb4))`},
+		{MySQL, `\'; DROP TABLE users; --`, `CONCAT(CHAR(92 USING utf8mb4), '''; DROP TABLE users; --')`},
+		{MySQL, "a`b\"c", "'a`b\"c'"},
+		{SQLServer, "o'brien", "N'o''brien'"},
+	}
+
+	for _, tt := range tests {
+		got := tt.d.Literal(tt.in)
+		if got != tt.want {
+			t.Errorf("Literal(%d, %q) = %s, attendu %s", tt.d, tt.in, got, tt.want)
+		}
+		// Avec NO_BACKSLASH_ESCAPES, \ est un caractère ordinaire: un littéral
+		// MySQL n'en contient jamais, pour être lu de la même façon dans les
+		// deux modes.
+		if tt.d == MySQL && strings.Contains(got, `\`) {
+			t.Errorf("Literal(MySQL, %q) = %s dépend de NO_BACKSLASH_ESCAPES", tt.in, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		d     Dialect
+		ident string
+		ok    bool
+	}{
+		{Postgres, "users", true},
+		{Postgres, "Mixed Case", true},
+		{Postgres, "select", true},
+		{Postgres, `a"b`, true},
+		{Postgres, strings.Repeat("a", 63), true},
+		{Postgres, strings.Repeat("a", 64), false},
+		{Postgres, strings.Repeat("é", 32), false}, // 64 octets
+		{Postgres, "", false},
+		{Postgres, "a\x00b", false},
+		{Postgres, "\xff", false},
+		{MySQL, "order", true},
+		{MySQL, "a`b", true},
+		{MySQL, strings.Repeat("a", 64), true},
+		{MySQL, strings.Repeat("a", 65), false},
+		{MySQL, "users ", false},
+		{MySQL, "a\x00b", false},
+	}
+
+	for _, tt := range tests {
+		err := tt.d.Validate(tt.ident)
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate(%d, %q) = %v, valide attendu: %v", tt.d, tt.ident, err, tt.ok)
+		}
+	}
+
+	if err := (Name{Schema: strings.Repeat("s", 64), Name: "users"}).Validate(Postgres); err == nil {
+		t.Error("Name.Validate: schéma trop long accepté")
+	}
+}
+
+func TestShorten(t *testing.T) {
+	long := strings.Repeat("table_", 20)
+	tests := []struct {
+		d     Dialect
+		ident string
+	}{
+		{Postgres, "paypayo_users_notify"},
+		{Postgres, strings.Repeat("a", 63)},
+		{Postgres, strings.Repeat("a", 64)},
+		{Postgres, long + "_insert_trigger"},
+		{Postgres, strings.Repeat("é", 40)},
+		{MySQL, strings.Repeat("a", 64)},
+		{MySQL, long + "_audit_archive"},
+		{MySQL, strings.Repeat("é", 40) + "_audit"},
+	}
+
+	for _, tt := range tests {
+		got := tt.d.Shorten(tt.ident)
+		if len(tt.ident) <= tt.d.MaxLength() {
+			if got != tt.ident {
+				t.Errorf("Shorten(%d, %q) = %q, inchangé attendu", tt.d, tt.ident, got)
+			}
+			continue
+		}
+		if len(got) > tt.d.MaxLength() {
+			t.Errorf("Shorten(%d, %q) = %q, %d octets", tt.d, tt.ident, got, len(got))
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Shorten(%d, %q) = %q, UTF-8 invalide", tt.d, tt.ident, got)
+		}
+		if err := tt.d.Validate(got); err != nil {
+			t.Errorf("Shorten(%d, %q): %v", tt.d, tt.ident, err)
+		}
+		if again := tt.d.Shorten(tt.ident); again != got {
+			t.Errorf("Shorten(%d, %q) instable: %q puis %q", tt.d, tt.ident, got, again)
+		}
+	}
+
+	// Deux noms longs de même préfixe restent distincts.
+	a := Postgres.Shorten(long + "_insert_trigger")
+	b := Postgres.Shorten(long + "_update_trigger")
+	if a == b {
+		t.Errorf("Shorten: collision %q", a)
+	}
+}
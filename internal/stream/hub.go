@@ -0,0 +1,417 @@
+// Package stream diffuse les événements aux abonnés (gRPC, SSE) avec un
+// offset croissant, et garde les derniers pour permettre la reprise.
+package stream
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"app-db-listener/internal/config"
+	"app-db-listener/internal/i18n"
+	"app-db-listener/internal/logger"
+	"app-db-listener/internal/metrics"
+	"app-db-listener/internal/notifier"
+	"app-db-listener/internal/render"
+)
+
+var (
+	// ErrUnknownPosition: l'offset ou l'id de reprise n'est plus conservé.
+	ErrUnknownPosition = i18n.NewError("position de reprise inconnue ou plus conservée")
+	// ErrSlowConsumer: l'abonné n'a pas suivi, son tampon est plein; il peut
+	// se réabonner depuis le dernier offset reçu.
+	ErrSlowConsumer = i18n.NewError("abonné trop lent, flux interrompu")
+	ErrClosed       = i18n.NewError("flux fermé")
+)
+
+// Entry est un événement et sa position dans le flux.
+type Entry struct {
+	Offset uint64                `json:"offset"`
+	Event  *notifier.ChangeEvent `json:"event"`
+}
+
+// Filter retient les événements par table, opération et valeur de colonne;
+// un critère vide laisse tout passer.
+type Filter struct {
+	tables     map[string]bool
+	operations map[string]bool
+	columns    map[string][]string
+}
+
+func NewFilter(tables, operations []string) Filter {
+	f := Filter{}
+	if len(tables) > 0 {
+		f.tables = make(map[string]bool, len(tables))
+		for _, t := range tables {
+			f.tables[t] = true
+		}
+	}
+	if len(operations) > 0 {
+		f.operations = make(map[string]bool, len(operations))
+		for _, op := range operations {
+			f.operations[strings.ToUpper(op)] = true
+		}
+	}
+	return f
+}
+
+// WhereColumn ne retient que les événements dont la colonne vaut l'une des
+// valeurs (comparées sous leur forme texte, null pour NULL).
+func (f *Filter) WhereColumn(name string, values ...string) {
+	if f.columns == nil {
+		f.columns = make(map[string][]string)
+	}
+	f.columns[name] = append(f.columns[name], values...)
+}
+
+func (f Filter) Match(event *notifier.ChangeEvent) bool {
+	if f.tables != nil && !f.tables[event.Table] {
+		return false
+	}
+	if f.operations != nil && !f.operations[event.Operation] {
+		return false
+	}
+	for name, values := range f.columns {
+		v, ok := event.Data[name]
+		if !ok || !slices.Contains(values, render.FormatValue(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Start indique où commence un abonnement: après un offset, après l'événement
+// d'un id, ou (zéro) aux nouveaux événements seulement.
+type Start struct {
+	Offset *uint64
+	ID     string
+}
+
+func (s Start) set() bool {
+	return s.Offset != nil || s.ID != ""
+}
+
+// Hub reçoit les événements du notifier (notifier.Sink) et les distribue aux
+// abonnés.
+type Hub struct {
+	mu           sync.Mutex
+	ring         []Entry
+	size         int
+	next         uint64
+	subs         map[*Subscription]struct{}
+	journal      *journal
+	clientBuffer int
+	closed       bool
+	logger       *logger.Logger
+}
+
+func New(cfg *config.StreamConfig, log *logger.Logger) (*Hub, error) {
+	h := &Hub{
+		size:         cfg.BufferSize,
+		next:         1,
+		subs:         make(map[*Subscription]struct{}),
+		clientBuffer: cfg.ClientBuffer,
+		logger:       log,
+	}
+
+	if cfg.Journal != "" {
+		j, err := openJournal(cfg.Journal, cfg.JournalMaxMB)
+		if err != nil {
+			return nil, err
+		}
+		tail, last, err := j.load(cfg.BufferSize)
+		if err != nil {
+			j.close()
+			return nil, err
+		}
+		h.journal, h.ring, h.next = j, tail, last+1
+		if last > 0 {
+			log.Info("Journal %s relu, reprise du flux à l'offset %d", cfg.Journal, h.next)
+		}
+	}
+
+	return h, nil
+}
+
+// Publish attribue un offset à l'événement, l'écrit au journal et le
+// transmet aux abonnés. Un abonné dont le tampon est plein est déconnecté.
+func (h *Hub) Publish(event *notifier.ChangeEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrClosed
+	}
+
+	e := Entry{Offset: h.next, Event: event}
+	if h.journal != nil {
+		if err := h.journal.append(e); err != nil {
+			return i18n.Errorf("erreur écriture journal: %w", err)
+		}
+	}
+	h.next++
+
+	h.ring = append(h.ring, e)
+	if len(h.ring) > h.size {
+		h.ring = h.ring[1:]
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.live <- e:
+		default:
+			h.logger.Warn("Abonné au flux déconnecté: tampon plein à l'offset %d", e.Offset)
+			h.drop(sub, ErrSlowConsumer)
+		}
+	}
+	return nil
+}
+
+// Subscribe ouvre un abonnement. Les événements antérieurs à start sont
+// relus dans le journal (ou la mémoire) avant les nouveaux.
+func (h *Hub) Subscribe(filter Filter, start Start) (*Subscription, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrClosed
+	}
+
+	sub := &Subscription{
+		hub:      h,
+		filter:   filter,
+		live:     make(chan Entry, h.clientBuffer),
+		liveFrom: h.next,
+	}
+
+	// Copie de la mémoire et ouverture du journal sous le verrou: aucune
+	// publication ni rotation ne peut s'intercaler avant l'inscription.
+	var ring []Entry
+	var reader *journalReader
+	var err error
+	if start.set() {
+		if h.journal != nil {
+			reader, err = h.journal.reader()
+		} else {
+			ring = append([]Entry(nil), h.ring...)
+		}
+	}
+	if err == nil {
+		h.subs[sub] = struct{}{}
+		metrics.StreamSubscribers.Add(1)
+	}
+	h.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if !start.set() {
+		return sub, nil
+	}
+
+	if reader != nil {
+		err = sub.seekJournal(reader, start)
+	} else {
+		err = sub.seekRing(ring, start)
+	}
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// drop retire l'abonné; h.mu doit être détenu.
+func (h *Hub) drop(sub *Subscription, err error) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	sub.err = err
+	close(sub.live)
+	metrics.StreamSubscribers.Add(-1)
+}
+
+// Close termine les abonnements et ferme le journal.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for sub := range h.subs {
+		h.drop(sub, ErrClosed)
+	}
+	if h.journal != nil {
+		return h.journal.close()
+	}
+	return nil
+}
+
+// Subscription reçoit les événements d'un abonné: d'abord le rattrapage
+// (journal ou mémoire), puis le flux en direct.
+type Subscription struct {
+	hub      *Hub
+	filter   Filter
+	live     chan Entry
+	liveFrom uint64 // premier offset reçu en direct
+	err      error  // cause de la fermeture, protégée par hub.mu
+
+	backlog []Entry
+	reader  *journalReader
+	after   uint64 // dernier offset relu dans le journal
+	checked bool   // continuité du journal vérifiée
+}
+
+func (s *Subscription) seekRing(ring []Entry, start Start) error {
+	after, err := s.resolve(start, func(yield func(Entry) bool) error {
+		for _, e := range ring {
+			if !yield(e) {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if after > 0 && len(ring) > 0 && ring[0].Offset > after+1 {
+		return ErrUnknownPosition
+	}
+	for _, e := range ring {
+		if e.Offset > after && s.filter.Match(e.Event) {
+			s.backlog = append(s.backlog, e)
+		}
+	}
+	return nil
+}
+
+func (s *Subscription) seekJournal(r *journalReader, start Start) error {
+	s.reader = r
+	if start.ID != "" {
+		// Le lecteur reste positionné juste après l'événement trouvé
+		_, err := s.resolve(start, func(yield func(Entry) bool) error {
+			for {
+				e, err := r.next()
+				if err == io.EOF || e.Offset >= s.liveFrom {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if !yield(e) {
+					return nil
+				}
+			}
+		})
+		return err
+	}
+
+	s.after = *start.Offset
+	if s.after >= s.liveFrom {
+		return ErrUnknownPosition
+	}
+	return nil
+}
+
+// resolve renvoie l'offset après lequel reprendre; scan parcourt les
+// événements conservés jusqu'à ce que yield renvoie false.
+func (s *Subscription) resolve(start Start, scan func(yield func(Entry) bool) error) (uint64, error) {
+	if start.ID == "" {
+		if *start.Offset >= s.liveFrom {
+			return 0, ErrUnknownPosition
+		}
+		return *start.Offset, nil
+	}
+
+	var found *Entry
+	err := scan(func(e Entry) bool {
+		if e.Event != nil && e.Event.ID == start.ID {
+			found = &e
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if found == nil {
+		return 0, ErrUnknownPosition
+	}
+	s.after = found.Offset
+	return found.Offset, nil
+}
+
+// Next renvoie l'événement suivant de l'abonnement.
+func (s *Subscription) Next(ctx context.Context) (Entry, error) {
+	if s.reader != nil {
+		e, err := s.nextFromJournal()
+		if err != io.EOF {
+			return e, err
+		}
+	}
+
+	if len(s.backlog) > 0 {
+		e := s.backlog[0]
+		s.backlog = s.backlog[1:]
+		return e, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return Entry{}, ctx.Err()
+	case e, ok := <-s.live:
+		if !ok {
+			s.hub.mu.Lock()
+			defer s.hub.mu.Unlock()
+			return Entry{}, s.err
+		}
+		return e, nil
+	}
+}
+
+func (s *Subscription) nextFromJournal() (Entry, error) {
+	for {
+		e, err := s.reader.next()
+		if err == nil && e.Offset >= s.liveFrom {
+			err = io.EOF
+		}
+		if err != nil {
+			s.reader.close()
+			s.reader = nil
+			return Entry{}, err
+		}
+
+		if e.Offset <= s.after {
+			continue
+		}
+		if !s.checked && s.after > 0 && e.Offset > s.after+1 {
+			// Le journal ne remonte plus jusqu'à la position demandée
+			s.reader.close()
+			s.reader = nil
+			return Entry{}, ErrUnknownPosition
+		}
+		s.after, s.checked = e.Offset, true
+		if s.filter.Match(e.Event) {
+			return e, nil
+		}
+	}
+}
+
+// Close désabonne; Next ne doit plus être appelé ensuite.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	s.hub.drop(s, ErrClosed)
+	s.hub.mu.Unlock()
+
+	if s.reader != nil {
+		s.reader.close()
+		s.reader = nil
+	}
+}
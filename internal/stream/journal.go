@@ -0,0 +1,162 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"app-db-listener/internal/i18n"
+)
+
+// journal conserve les événements publiés sur disque (JSONL), pour reprendre
+// un abonnement après un redémarrage. Au-delà de maxBytes, le fichier devient
+// <path>.1 (l'ancien est supprimé) et un nouveau est ouvert.
+type journal struct {
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+func openJournal(path string, maxMB int) (*journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, i18n.Errorf("erreur ouverture journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &journal{path: path, maxBytes: int64(maxMB) << 20, file: f, size: info.Size()}, nil
+}
+
+// load relit le journal: renvoie les keep derniers événements et le dernier
+// offset écrit.
+func (j *journal) load(keep int) ([]Entry, uint64, error) {
+	r, err := j.reader()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.close()
+
+	var tail []Entry
+	var last uint64
+	for {
+		e, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		last = e.Offset
+		tail = append(tail, e)
+		if len(tail) > keep {
+			tail = tail[1:]
+		}
+	}
+	return tail, last, nil
+}
+
+func (j *journal) append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if j.size > 0 && j.size+int64(len(line)) > j.maxBytes {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.file.Write(line)
+	j.size += int64(n)
+	return err
+}
+
+func (j *journal) rotate() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(j.path, j.path+".1"); err != nil {
+		return i18n.Errorf("erreur rotation journal: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return i18n.Errorf("erreur ouverture journal: %w", err)
+	}
+	j.file, j.size = f, 0
+	return nil
+}
+
+// reader ouvre les deux fichiers du journal, du plus ancien au plus récent.
+// Les descripteurs restent valides même si une rotation a lieu ensuite.
+func (j *journal) reader() (*journalReader, error) {
+	r := &journalReader{}
+	for _, path := range []string{j.path + ".1", j.path} {
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			r.close()
+			return nil, i18n.Errorf("erreur lecture journal: %w", err)
+		}
+		r.files = append(r.files, f)
+	}
+	return r, nil
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+type journalReader struct {
+	files   []*os.File
+	scanner *bufio.Scanner
+}
+
+// next renvoie l'événement suivant, io.EOF à la fin du journal. Une ligne
+// incomplète (écriture en cours, arrêt brutal) est ignorée.
+func (r *journalReader) next() (Entry, error) {
+	for {
+		if r.scanner == nil {
+			if len(r.files) == 0 {
+				return Entry{}, io.EOF
+			}
+			r.scanner = bufio.NewScanner(r.files[0])
+			r.scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		}
+
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return Entry{}, i18n.Errorf("erreur lecture journal: %w", err)
+			}
+			r.files[0].Close()
+			r.files = r.files[1:]
+			r.scanner = nil
+			continue
+		}
+
+		var e Entry
+		dec := json.NewDecoder(bytes.NewReader(r.scanner.Bytes()))
+		dec.UseNumber()
+		if err := dec.Decode(&e); err != nil {
+			continue
+		}
+		return e, nil
+	}
+}
+
+func (r *journalReader) close() {
+	for _, f := range r.files {
+		f.Close()
+	}
+	r.files = nil
+}